@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Calevin/go_htmx_crud/database"
+	"github.com/Calevin/go_htmx_crud/internal/backup"
+	"github.com/Calevin/go_htmx_crud/internal/handlers"
+	"github.com/Calevin/go_htmx_crud/internal/snapshot"
+)
+
+// defaultDBPath es la base de datos que usa el servidor.
+const defaultDBPath = "./crud.db"
+
+// runCommand ejecuta un comando de la linea de comandos y termina el proceso
+// con un error si falla.
+func runCommand(args []string) {
+	var err error
+	switch args[0] {
+	case "backup":
+		err = backupCommand(args[1:])
+	case "restore":
+		err = restoreCommand(args[1:])
+	case "snapshot":
+		err = snapshotCommand(args[1:])
+	case "snapshots":
+		err = listSnapshotsCommand(args[1:])
+	case "snapshot-restore":
+		err = restoreSnapshotCommand(args[1:])
+	default:
+		err = fmt.Errorf("comando desconocido %q. Comandos: backup, restore, snapshot, snapshots, snapshot-restore", args[0])
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// backupCommand escribe una copia de seguridad en JSON de toda la base.
+//
+//	go_htmx_crud backup [-db ./crud.db] [-o backup.json]
+func backupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "base de datos SQLite a respaldar")
+	output := fs.String("o", "", "archivo de salida (por defecto la salida estándar)")
+	fs.Parse(args)
+
+	conn := database.InitDB(*dbPath)
+	defer conn.Close()
+
+	dump, err := backup.Export(context.Background(), conn)
+	if err != nil {
+		return fmt.Errorf("error leyendo la base de datos: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := backup.Write(w, dump); err != nil {
+		return fmt.Errorf("error escribiendo el backup: %w", err)
+	}
+
+	log.Printf("Backup generado: %d usuarios, %d espacios de trabajo, %d tags, %d notas", len(dump.Users), len(dump.Workspaces), len(dump.Tags), len(dump.Notes))
+	return nil
+}
+
+// restoreCommand carga una copia de seguridad en una base de datos vacia.
+//
+//	go_htmx_crud restore [-db ./crud.db] backup.json
+func restoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "base de datos SQLite donde restaurar (debe estar vacía)")
+	check := fs.Bool("check", false, "solo valida el archivo, sin restaurar")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("uso: restore [-db base.db] [-check] backup.json")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dump, err := backup.Read(f)
+	if err != nil {
+		return err
+	}
+	if *check {
+		if err := backup.Validate(dump); err != nil {
+			return err
+		}
+		log.Printf("Backup válido: %d usuarios, %d tags, %d notas", len(dump.Users), len(dump.Tags), len(dump.Notes))
+		return nil
+	}
+
+	conn := database.InitDB(*dbPath)
+	defer conn.Close()
+
+	stats, err := backup.Restore(context.Background(), conn, mdRenderer, dump)
+	if err != nil {
+		return err
+	}
+	log.Printf("Backup restaurado: %d usuarios, %d espacios de trabajo, %d miembros, %d tags, %d notas, %d relaciones nota-tag, %d enlaces, %d notas compartidas, %d comentarios",
+		stats.Users, stats.Workspaces, stats.WorkspaceMembers, stats.Tags, stats.Notes, stats.NoteTags, stats.NoteLinks, stats.NoteShares, stats.NoteComments)
+	return nil
+}
+
+// snapshotCommand hace una copia del archivo de la base en SNAPSHOT_DIR y
+// elimina las que exceden la retención.
+//
+//	go_htmx_crud snapshot [-db ./crud.db]
+func snapshotCommand(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "base de datos SQLite a copiar")
+	fs.Parse(args)
+
+	conn := database.InitDB(*dbPath)
+	defer conn.Close()
+
+	s, err := newSnapshotManager().Create(context.Background(), conn)
+	if err != nil {
+		return err
+	}
+	log.Printf("Copia creada y verificada: %s (%s)", s.Path, handlers.FormatSize(s.Size))
+	return nil
+}
+
+// listSnapshotsCommand lista las copias de SNAPSHOT_DIR.
+//
+//	go_htmx_crud snapshots
+func listSnapshotsCommand(args []string) error {
+	list, err := newSnapshotManager().List()
+	if err != nil {
+		return err
+	}
+	for _, s := range list {
+		fmt.Printf("%s\t%s\t%s\n", s.Name, s.CreatedAt.Local().Format("2006-01-02 15:04:05"), handlers.FormatSize(s.Size))
+	}
+	return nil
+}
+
+// restoreSnapshotCommand reemplaza la base por una copia, que puede indicarse
+// por su nombre en SNAPSHOT_DIR o por su ruta. El servidor tiene que estar detenido.
+//
+//	go_htmx_crud snapshot-restore [-db ./crud.db] crud-20261019T053200Z.db
+func restoreSnapshotCommand(args []string) error {
+	fs := flag.NewFlagSet("snapshot-restore", flag.ExitOnError)
+	dbPath := fs.String("db", defaultDBPath, "base de datos SQLite a reemplazar")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("uso: snapshot-restore [-db base.db] copia")
+	}
+
+	path := fs.Arg(0)
+	if s, err := newSnapshotManager().Get(path); err == nil {
+		path = s.Path
+	} else if !errors.Is(err, snapshot.ErrNotFound) {
+		return err
+	}
+
+	previous, err := snapshot.Restore(context.Background(), path, *dbPath)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		log.Printf("La base anterior se guardó en %s", previous)
+	}
+	log.Printf("Copia %s restaurada en %s", path, *dbPath)
+	return nil
+}
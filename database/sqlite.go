@@ -1,7 +1,9 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 
@@ -12,7 +14,8 @@ import (
 // InitDB inicializa la conexión a la base de datos y ejecuta el esquema.
 func InitDB(filepath string) *sql.DB {
 	// Abre la conexión con la base de datos. Si el archivo no existe, lo crea.
-	db, err := sql.Open("sqlite3", filepath)
+	// Se activan las foreign keys para que funcionen los ON DELETE CASCADE.
+	db, err := sql.Open("sqlite3", filepath+"?_foreign_keys=on")
 	if err != nil {
 		log.Fatalf("Error abriendo la base de datos: %v", err)
 	}
@@ -29,6 +32,16 @@ func InitDB(filepath string) *sql.DB {
 		log.Fatalf("Error aplicando el esquema: %v", err)
 	}
 
+	// Agrega las columnas nuevas a las tablas creadas con un esquema anterior
+	if err = migrateColumns(db); err != nil {
+		log.Fatalf("Error migrando columnas: %v", err)
+	}
+
+	// Las tags pasan a ser unicas por espacio de trabajo en vez de globales
+	if err = migrateTags(db); err != nil {
+		log.Fatalf("Error migrando las tags: %v", err)
+	}
+
 	return db
 }
 
@@ -43,3 +56,197 @@ func executeSchema(db *sql.DB) error {
 	_, err = db.Exec(string(schema))
 	return err
 }
+
+// columnMigration describe una columna agregada al esquema despues de que la
+// tabla ya existia. `CREATE TABLE IF NOT EXISTS` no modifica tablas existentes,
+// por eso las columnas nuevas se agregan con ALTER TABLE.
+type columnMigration struct {
+	table  string
+	column string
+	// definition es la definicion usada en ALTER TABLE ... ADD COLUMN.
+	// SQLite no permite defaults no constantes (ej. CURRENT_TIMESTAMP) aca.
+	definition string
+	// backfill es un UPDATE opcional para completar las filas existentes.
+	backfill string
+}
+
+var columnMigrations = []columnMigration{
+	{
+		table:      "notes",
+		column:     "created_at",
+		definition: "DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'",
+		backfill:   "UPDATE notes SET created_at = CURRENT_TIMESTAMP",
+	},
+	{
+		table:      "notes",
+		column:     "updated_at",
+		definition: "DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'",
+		backfill:   "UPDATE notes SET updated_at = created_at",
+	},
+	{
+		table:      "notes",
+		column:     "deleted_at",
+		definition: "DATETIME",
+	},
+	{
+		table:      "notes",
+		column:     "version",
+		definition: "INTEGER NOT NULL DEFAULT 1",
+	},
+	{
+		table:      "attachments",
+		column:     "thumbnail_sha256",
+		definition: "TEXT",
+	},
+	{
+		table:      "notes",
+		column:     "pinned_at",
+		definition: "DATETIME",
+	},
+	{
+		table:      "notes",
+		column:     "archived_at",
+		definition: "DATETIME",
+	},
+	{
+		// Claves de internal/rank. Las notas existentes conservan el orden
+		// por id descendente: "h" seguido de 8 digitos es una clave valida.
+		table:      "notes",
+		column:     "position",
+		definition: "TEXT NOT NULL DEFAULT ''",
+		backfill:   "UPDATE notes SET position = printf('h%08d', (SELECT MAX(id) FROM notes) - id)",
+	},
+	{
+		table:      "notes",
+		column:     "due_at",
+		definition: "DATETIME",
+	},
+	{
+		table:      "reminders",
+		column:     "claimed_at",
+		definition: "DATETIME",
+	},
+	{
+		table:      "users",
+		column:     "calendar_token",
+		definition: "TEXT",
+	},
+	{
+		// Las notas existentes pasan a ser del primer usuario
+		table:      "notes",
+		column:     "user_id",
+		definition: "INTEGER REFERENCES users(id) ON DELETE CASCADE",
+		backfill:   "UPDATE notes SET user_id = (SELECT MIN(id) FROM users)",
+	},
+	{
+		table:      "public_links",
+		column:     "failed_attempts",
+		definition: "INTEGER NOT NULL DEFAULT 0",
+	},
+	{
+		table:      "public_links",
+		column:     "locked_until",
+		definition: "DATETIME",
+	},
+	{
+		// NULL es el espacio personal del dueño de la nota
+		table:      "notes",
+		column:     "workspace_id",
+		definition: "INTEGER REFERENCES workspaces(id) ON DELETE CASCADE",
+	},
+}
+
+func migrateColumns(db *sql.DB) error {
+	for _, m := range columnMigrations {
+		exists, err := columnExists(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Agregando columna %s.%s", m.table, m.column)
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+		if m.backfill != "" {
+			if _, err := db.Exec(m.backfill); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// columnExists indica si la tabla ya tiene la columna.
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// tagsUniqueIndex hace que el nombre de una tag sea unico dentro de su espacio
+// de trabajo. Es un indice sobre una expresion porque en un UNIQUE de SQLite dos
+// NULL son distintos, y las tags generales tienen workspace_id NULL.
+const tagsUniqueIndex = `CREATE UNIQUE INDEX IF NOT EXISTS tags_workspace_nombre
+    ON tags (IFNULL(workspace_id, 0), nombre)`
+
+// migrateTags reconstruye la tabla tags de un esquema anterior, donde el nombre
+// era UNIQUE en toda la base, para agregar workspace_id y que el nombre sea
+// unico dentro de cada espacio. SQLite no permite quitar una restriccion con
+// ALTER TABLE, asi que se copia la tabla como indica su documentacion.
+func migrateTags(db *sql.DB) error {
+	exists, err := columnExists(db, "tags", "workspace_id")
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err := db.Exec(tagsUniqueIndex)
+		return err
+	}
+
+	log.Println("Reconstruyendo la tabla tags")
+
+	// foreign_keys es por conexion y no se puede cambiar dentro de una
+	// transaccion: se usa una conexion dedicada para apagarlas mientras tanto
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE tags_nueva (
+    "id"    INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "nombre" TEXT NOT NULL,
+    "color"  TEXT,
+    "workspace_id" INTEGER REFERENCES workspaces(id) ON DELETE CASCADE
+)`,
+		"INSERT INTO tags_nueva (id, nombre, color) SELECT id, nombre, color FROM tags",
+		"DROP TABLE tags",
+		"ALTER TABLE tags_nueva RENAME TO tags",
+		tagsUniqueIndex,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,331 @@
+// Package backup genera y restaura copias de seguridad de todos los datos en
+// JSON, independientes de SQLite: usuarios, espacios de trabajo, notas, tags,
+// comentarios y las relaciones entre ellos.
+//
+// Los ids del archivo solo sirven para relacionar los registros entre si; al
+// restaurar cada registro recibe un id nuevo. No se incluyen las revisiones,
+// los adjuntos, los recordatorios, los enlaces públicos ni las invitaciones.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Calevin/go_htmx_crud/internal/db"
+)
+
+// SchemaVersion es la version del formato del archivo. Se incrementa con
+// cada cambio incompatible.
+//
+// La version 2 agrega el dueño de cada nota y las notas compartidas. Las notas
+// de una copia de la version 1 se restauran como del primer usuario.
+//
+// La version 3 agrega los espacios de trabajo, sus miembros y el espacio de
+// cada nota y cada tag. En las copias anteriores todo es del espacio personal.
+//
+// La version 4 agrega los comentarios de las notas.
+const SchemaVersion = 4
+
+// Dump es el contenido de una copia de seguridad.
+type Dump struct {
+	SchemaVersion    int               `json:"schema_version"`
+	CreatedAt        time.Time         `json:"created_at"`
+	Users            []User            `json:"users"`
+	Workspaces       []Workspace       `json:"workspaces,omitempty"`
+	WorkspaceMembers []WorkspaceMember `json:"workspace_members,omitempty"`
+	Tags             []Tag             `json:"tags"`
+	Notes            []Note            `json:"notes"`
+	NoteTags         []NoteTag         `json:"note_tags"`
+	NoteLinks        []NoteLink        `json:"note_links"`
+	NoteShares       []NoteShare       `json:"note_shares,omitempty"`
+	NoteComments     []NoteComment     `json:"note_comments,omitempty"`
+}
+
+// User es un usuario, con el hash de su contraseña.
+type User struct {
+	ID            int64   `json:"id"`
+	Username      string  `json:"username"`
+	PasswordHash  string  `json:"password_hash"`
+	CalendarToken *string `json:"calendar_token,omitempty"`
+}
+
+// Workspace es un espacio de trabajo.
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Nombre    string    `json:"nombre"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceMember es un usuario que es parte de un espacio de trabajo.
+type WorkspaceMember struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Tag es un tag. Sin espacio de trabajo es un tag general.
+type Tag struct {
+	ID          int64   `json:"id"`
+	Nombre      string  `json:"nombre"`
+	Color       *string `json:"color,omitempty"`
+	WorkspaceID *int64  `json:"workspace_id,omitempty"`
+}
+
+// Note es una nota, incluidas las que estan en la papelera.
+type Note struct {
+	ID          int64      `json:"id"`
+	Nombre      string     `json:"nombre"`
+	Contenido   *string    `json:"contenido,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Version     int64      `json:"version"`
+	PinnedAt    *time.Time `json:"pinned_at,omitempty"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	Position    string     `json:"position"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	UserID      *int64     `json:"user_id,omitempty"`
+	WorkspaceID *int64     `json:"workspace_id,omitempty"`
+}
+
+// NoteTag asocia una nota con un tag.
+type NoteTag struct {
+	NoteID int64 `json:"note_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+// NoteLink es un enlace [[...]] de una nota a otra.
+type NoteLink struct {
+	SourceID int64 `json:"source_id"`
+	TargetID int64 `json:"target_id"`
+}
+
+// NoteShare es una nota compartida con un usuario que no es su dueño.
+type NoteShare struct {
+	NoteID     int64     `json:"note_id"`
+	UserID     int64     `json:"user_id"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NoteComment es un comentario de una nota.
+type NoteComment struct {
+	ID        int64      `json:"id"`
+	NoteID    int64      `json:"note_id"`
+	UserID    int64      `json:"user_id"`
+	Contenido string     `json:"contenido"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Export lee todos los datos de la base. Todas las lecturas se hacen en una
+// misma transaccion: con el servidor andando, las relaciones no pueden apuntar
+// a registros creados despues de leer las tablas anteriores.
+func Export(ctx context.Context, conn *sql.DB) (*Dump, error) {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return export(ctx, db.New(tx))
+}
+
+func export(ctx context.Context, queries *db.Queries) (*Dump, error) {
+	d := &Dump{
+		SchemaVersion:    SchemaVersion,
+		CreatedAt:        time.Now().UTC(),
+		Users:            []User{},
+		Workspaces:       []Workspace{},
+		WorkspaceMembers: []WorkspaceMember{},
+		Tags:             []Tag{},
+		Notes:            []Note{},
+		NoteTags:         []NoteTag{},
+		NoteLinks:        []NoteLink{},
+		NoteShares:       []NoteShare{},
+		NoteComments:     []NoteComment{},
+	}
+
+	users, err := queries.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		d.Users = append(d.Users, User{
+			ID:            u.ID,
+			Username:      u.Username,
+			PasswordHash:  u.PasswordHash,
+			CalendarToken: fromNullString(u.CalendarToken),
+		})
+	}
+
+	workspaces, err := queries.ListAllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workspaces {
+		d.Workspaces = append(d.Workspaces, Workspace{ID: w.ID, Nombre: w.Nombre, CreatedAt: w.CreatedAt.UTC()})
+	}
+
+	members, err := queries.ListAllWorkspaceMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		d.WorkspaceMembers = append(d.WorkspaceMembers, WorkspaceMember{
+			WorkspaceID: m.WorkspaceID,
+			UserID:      m.UserID,
+			Role:        m.Role,
+			CreatedAt:   m.CreatedAt.UTC(),
+		})
+	}
+
+	tags, err := queries.ListAllTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		d.Tags = append(d.Tags, Tag{
+			ID:          t.ID,
+			Nombre:      t.Nombre,
+			Color:       fromNullString(t.Color),
+			WorkspaceID: fromNullInt64(t.WorkspaceID),
+		})
+	}
+
+	notes, err := queries.ListAllNotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		d.Notes = append(d.Notes, Note{
+			ID:          n.ID,
+			Nombre:      n.Nombre,
+			Contenido:   fromNullString(n.Contenido),
+			CreatedAt:   n.CreatedAt.UTC(),
+			UpdatedAt:   n.UpdatedAt.UTC(),
+			DeletedAt:   fromNullTime(n.DeletedAt),
+			Version:     n.Version,
+			PinnedAt:    fromNullTime(n.PinnedAt),
+			ArchivedAt:  fromNullTime(n.ArchivedAt),
+			Position:    n.Position,
+			DueAt:       fromNullTime(n.DueAt),
+			UserID:      fromNullInt64(n.UserID),
+			WorkspaceID: fromNullInt64(n.WorkspaceID),
+		})
+	}
+
+	noteTags, err := queries.ListNoteTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, nt := range noteTags {
+		d.NoteTags = append(d.NoteTags, NoteTag{NoteID: nt.NoteID, TagID: nt.TagID})
+	}
+
+	links, err := queries.ListNoteLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		d.NoteLinks = append(d.NoteLinks, NoteLink{SourceID: l.SourceID, TargetID: l.TargetID})
+	}
+
+	shares, err := queries.ListNoteShares(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shares {
+		d.NoteShares = append(d.NoteShares, NoteShare{
+			NoteID:     s.NoteID,
+			UserID:     s.UserID,
+			Permission: s.Permission,
+			CreatedAt:  s.CreatedAt.UTC(),
+		})
+	}
+
+	comments, err := queries.ListAllComments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		d.NoteComments = append(d.NoteComments, NoteComment{
+			ID:        c.ID,
+			NoteID:    c.NoteID,
+			UserID:    c.UserID,
+			Contenido: c.Contenido,
+			CreatedAt: c.CreatedAt.UTC(),
+			UpdatedAt: fromNullTime(c.UpdatedAt),
+		})
+	}
+
+	return d, nil
+}
+
+// Write escribe la copia en JSON.
+func Write(w io.Writer, d *Dump) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// Read lee una copia en JSON y verifica que su version sea compatible. Los
+// campos desconocidos se rechazan para no perder datos sin avisar.
+func Read(r io.Reader) (*Dump, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var d Dump
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("archivo de backup inválido: %w", err)
+	}
+	if d.SchemaVersion < 1 || d.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("versión de backup no soportada: %d (se admite hasta la %d)", d.SchemaVersion, SchemaVersion)
+	}
+	return &d, nil
+}
+
+// fromNullString devuelve nil si s no es valido.
+func fromNullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// fromNullInt64 devuelve nil si i no es valido.
+func fromNullInt64(i sql.NullInt64) *int64 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int64
+}
+
+// fromNullTime devuelve nil si t no es valido.
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// toNullString convierte un valor opcional en sql.NullString.
+func toNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// toNullTime convierte un valor opcional en sql.NullTime.
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,380 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+)
+
+// ErrNotEmpty indica que la base donde se quiere restaurar ya tiene datos.
+var ErrNotEmpty = errors.New("la base de datos no está vacía: restaura en una base nueva")
+
+// maxProblems es la cantidad maxima de problemas que informa Validate.
+const maxProblems = 20
+
+// Validate verifica la integridad de la copia: ids unicos, campos obligatorios,
+// nombres unicos y que las relaciones apunten a registros que existen.
+// Devuelve todos los problemas encontrados (hasta maxProblems) en un solo error.
+func Validate(d *Dump) error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	users := make(map[int64]bool)
+	usernames := make(map[string]bool)
+	for _, u := range d.Users {
+		if users[u.ID] {
+			add("usuario %d repetido", u.ID)
+		}
+		users[u.ID] = true
+		if u.Username == "" || u.PasswordHash == "" {
+			add("usuario %d sin nombre o sin contraseña", u.ID)
+		}
+		if usernames[u.Username] {
+			add("usuario %q repetido", u.Username)
+		}
+		usernames[u.Username] = true
+	}
+
+	workspaces := make(map[int64]bool)
+	for _, w := range d.Workspaces {
+		if workspaces[w.ID] {
+			add("espacio de trabajo %d repetido", w.ID)
+		}
+		workspaces[w.ID] = true
+		if w.Nombre == "" {
+			add("espacio de trabajo %d sin nombre", w.ID)
+		}
+	}
+
+	members := make(map[[2]int64]bool)
+	for _, m := range d.WorkspaceMembers {
+		if !workspaces[m.WorkspaceID] {
+			add("workspace_members: el espacio %d no existe", m.WorkspaceID)
+		}
+		if !users[m.UserID] {
+			add("workspace_members: el usuario %d no existe", m.UserID)
+		}
+		if m.Role != "admin" && m.Role != "miembro" && m.Role != "lector" {
+			add("workspace_members: rol inválido %q", m.Role)
+		}
+		key := [2]int64{m.WorkspaceID, m.UserID}
+		if members[key] {
+			add("workspace_members: el usuario %d está dos veces en el espacio %d", m.UserID, m.WorkspaceID)
+		}
+		members[key] = true
+	}
+
+	tags := make(map[int64]bool)
+	// Los nombres son unicos dentro de cada espacio; 0 son los tags generales
+	tagNames := make(map[int64]map[string]bool)
+	for _, t := range d.Tags {
+		if tags[t.ID] {
+			add("tag %d repetido", t.ID)
+		}
+		tags[t.ID] = true
+		if t.Nombre == "" {
+			add("tag %d sin nombre", t.ID)
+		}
+		var workspace int64
+		if t.WorkspaceID != nil {
+			workspace = *t.WorkspaceID
+			if !workspaces[workspace] {
+				add("tag %d: el espacio de trabajo %d no existe", t.ID, workspace)
+			}
+		}
+		if tagNames[workspace] == nil {
+			tagNames[workspace] = make(map[string]bool)
+		}
+		if tagNames[workspace][t.Nombre] {
+			add("tag %q repetido", t.Nombre)
+		}
+		tagNames[workspace][t.Nombre] = true
+	}
+
+	notes := make(map[int64]bool)
+	for _, n := range d.Notes {
+		if notes[n.ID] {
+			add("nota %d repetida", n.ID)
+		}
+		notes[n.ID] = true
+		if n.Nombre == "" {
+			add("nota %d sin nombre", n.ID)
+		}
+		if n.Version < 1 {
+			add("nota %d con versión inválida %d", n.ID, n.Version)
+		}
+		if n.UserID != nil && !users[*n.UserID] {
+			add("nota %d: el usuario %d no existe", n.ID, *n.UserID)
+		}
+		if n.WorkspaceID != nil && !workspaces[*n.WorkspaceID] {
+			add("nota %d: el espacio de trabajo %d no existe", n.ID, *n.WorkspaceID)
+		}
+	}
+
+	noteTags := make(map[NoteTag]bool)
+	for _, nt := range d.NoteTags {
+		if !notes[nt.NoteID] {
+			add("note_tags: la nota %d no existe", nt.NoteID)
+		}
+		if !tags[nt.TagID] {
+			add("note_tags: el tag %d no existe", nt.TagID)
+		}
+		if noteTags[nt] {
+			add("note_tags: relación %d-%d repetida", nt.NoteID, nt.TagID)
+		}
+		noteTags[nt] = true
+	}
+
+	links := make(map[NoteLink]bool)
+	for _, l := range d.NoteLinks {
+		if !notes[l.SourceID] || !notes[l.TargetID] {
+			add("note_links: el enlace %d-%d apunta a una nota que no existe", l.SourceID, l.TargetID)
+		}
+		if links[l] {
+			add("note_links: enlace %d-%d repetido", l.SourceID, l.TargetID)
+		}
+		links[l] = true
+	}
+
+	shares := make(map[[2]int64]bool)
+	for _, s := range d.NoteShares {
+		if !notes[s.NoteID] {
+			add("note_shares: la nota %d no existe", s.NoteID)
+		}
+		if !users[s.UserID] {
+			add("note_shares: el usuario %d no existe", s.UserID)
+		}
+		if s.Permission != "lectura" && s.Permission != "edicion" {
+			add("note_shares: permiso inválido %q", s.Permission)
+		}
+		key := [2]int64{s.NoteID, s.UserID}
+		if shares[key] {
+			add("note_shares: nota %d compartida dos veces con el usuario %d", s.NoteID, s.UserID)
+		}
+		shares[key] = true
+	}
+
+	comments := make(map[int64]bool)
+	for _, c := range d.NoteComments {
+		if comments[c.ID] {
+			add("comentario %d repetido", c.ID)
+		}
+		comments[c.ID] = true
+		if !notes[c.NoteID] {
+			add("comentario %d: la nota %d no existe", c.ID, c.NoteID)
+		}
+		if !users[c.UserID] {
+			add("comentario %d: el usuario %d no existe", c.ID, c.UserID)
+		}
+		if c.Contenido == "" {
+			add("comentario %d vacío", c.ID)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxProblems {
+		problems = append(problems[:maxProblems], fmt.Sprintf("y %d problemas más", len(problems)-maxProblems))
+	}
+	return errors.New("backup inconsistente:\n  " + strings.Join(problems, "\n  "))
+}
+
+// Stats es la cantidad de registros restaurados.
+type Stats struct {
+	Users, Workspaces, WorkspaceMembers, Tags, Notes, NoteTags, NoteLinks, NoteShares, NoteComments int
+}
+
+// Restore valida la copia y la carga en una base vacia, en una sola
+// transaccion. Los registros reciben ids nuevos y las relaciones, incluidos
+// los enlaces [[#id]] del contenido de las notas, se traducen a esos ids.
+func Restore(ctx context.Context, conn *sql.DB, renderer *markdown.Renderer, d *Dump) (Stats, error) {
+	var stats Stats
+	if err := Validate(d); err != nil {
+		return stats, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback()
+	queries := db.New(tx)
+
+	counts, err := queries.CountBackupRows(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if counts.Users+counts.Notes+counts.Tags+counts.Workspaces > 0 {
+		return stats, ErrNotEmpty
+	}
+
+	userIDs := make(map[int64]int64, len(d.Users))
+	// Dueño de las notas sin usuario (copias de la version 1)
+	var defaultOwner sql.NullInt64
+	for _, u := range d.Users {
+		id, err := queries.InsertBackupUser(ctx, db.InsertBackupUserParams{
+			Username:      u.Username,
+			PasswordHash:  u.PasswordHash,
+			CalendarToken: toNullString(u.CalendarToken),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("usuario %d: %w", u.ID, err)
+		}
+		userIDs[u.ID] = id
+		if !defaultOwner.Valid {
+			defaultOwner = sql.NullInt64{Int64: id, Valid: true}
+		}
+		stats.Users++
+	}
+
+	workspaceIDs := make(map[int64]int64, len(d.Workspaces))
+	for _, w := range d.Workspaces {
+		id, err := queries.InsertBackupWorkspace(ctx, db.InsertBackupWorkspaceParams{
+			Nombre:    w.Nombre,
+			CreatedAt: w.CreatedAt,
+		})
+		if err != nil {
+			return stats, fmt.Errorf("espacio de trabajo %d: %w", w.ID, err)
+		}
+		workspaceIDs[w.ID] = id
+		stats.Workspaces++
+	}
+	// workspace traduce el espacio opcional de una nota o un tag
+	workspace := func(id *int64) sql.NullInt64 {
+		if id == nil {
+			return sql.NullInt64{}
+		}
+		return sql.NullInt64{Int64: workspaceIDs[*id], Valid: true}
+	}
+
+	for _, m := range d.WorkspaceMembers {
+		err := queries.InsertBackupWorkspaceMember(ctx, db.InsertBackupWorkspaceMemberParams{
+			WorkspaceID: workspaceIDs[m.WorkspaceID],
+			UserID:      userIDs[m.UserID],
+			Role:        m.Role,
+			CreatedAt:   m.CreatedAt,
+		})
+		if err != nil {
+			return stats, fmt.Errorf("workspace_members %d-%d: %w", m.WorkspaceID, m.UserID, err)
+		}
+		stats.WorkspaceMembers++
+	}
+
+	tagIDs := make(map[int64]int64, len(d.Tags))
+	for _, t := range d.Tags {
+		tag, err := queries.CreateTag(ctx, db.CreateTagParams{
+			Nombre:      t.Nombre,
+			Color:       toNullString(t.Color),
+			WorkspaceID: workspace(t.WorkspaceID),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("tag %d: %w", t.ID, err)
+		}
+		tagIDs[t.ID] = tag.ID
+		stats.Tags++
+	}
+
+	noteIDs := make(map[int64]int64, len(d.Notes))
+	for _, n := range d.Notes {
+		owner := defaultOwner
+		if n.UserID != nil {
+			owner = sql.NullInt64{Int64: userIDs[*n.UserID], Valid: true}
+		}
+		id, err := queries.InsertBackupNote(ctx, db.InsertBackupNoteParams{
+			Nombre:      n.Nombre,
+			Contenido:   toNullString(n.Contenido),
+			CreatedAt:   n.CreatedAt,
+			UpdatedAt:   n.UpdatedAt,
+			DeletedAt:   toNullTime(n.DeletedAt),
+			Version:     n.Version,
+			PinnedAt:    toNullTime(n.PinnedAt),
+			ArchivedAt:  toNullTime(n.ArchivedAt),
+			Position:    n.Position,
+			DueAt:       toNullTime(n.DueAt),
+			UserID:      owner,
+			WorkspaceID: workspace(n.WorkspaceID),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("nota %d: %w", n.ID, err)
+		}
+		noteIDs[n.ID] = id
+		stats.Notes++
+	}
+
+	// Los ids de las notas se conocen recien despues de crearlas todas
+	for _, n := range d.Notes {
+		if n.Contenido == nil {
+			continue
+		}
+		contenido := renderer.RemapWikiLinkIDs(*n.Contenido, noteIDs)
+		if contenido == *n.Contenido {
+			continue
+		}
+		err := queries.SetBackupNoteContent(ctx, db.SetBackupNoteContentParams{
+			ID:        noteIDs[n.ID],
+			Contenido: sql.NullString{String: contenido, Valid: true},
+		})
+		if err != nil {
+			return stats, fmt.Errorf("nota %d: %w", n.ID, err)
+		}
+	}
+
+	for _, nt := range d.NoteTags {
+		err := queries.LinkTagToNote(ctx, db.LinkTagToNoteParams{
+			NoteID: noteIDs[nt.NoteID],
+			TagID:  tagIDs[nt.TagID],
+		})
+		if err != nil {
+			return stats, fmt.Errorf("note_tags %d-%d: %w", nt.NoteID, nt.TagID, err)
+		}
+		stats.NoteTags++
+	}
+
+	for _, l := range d.NoteLinks {
+		err := queries.CreateNoteLink(ctx, db.CreateNoteLinkParams{
+			SourceID: noteIDs[l.SourceID],
+			TargetID: noteIDs[l.TargetID],
+		})
+		if err != nil {
+			return stats, fmt.Errorf("note_links %d-%d: %w", l.SourceID, l.TargetID, err)
+		}
+		stats.NoteLinks++
+	}
+
+	for _, s := range d.NoteShares {
+		err := queries.InsertBackupNoteShare(ctx, db.InsertBackupNoteShareParams{
+			NoteID:     noteIDs[s.NoteID],
+			UserID:     userIDs[s.UserID],
+			Permission: s.Permission,
+			CreatedAt:  s.CreatedAt,
+		})
+		if err != nil {
+			return stats, fmt.Errorf("note_shares %d-%d: %w", s.NoteID, s.UserID, err)
+		}
+		stats.NoteShares++
+	}
+
+	for _, c := range d.NoteComments {
+		err := queries.InsertBackupComment(ctx, db.InsertBackupCommentParams{
+			NoteID:    noteIDs[c.NoteID],
+			UserID:    userIDs[c.UserID],
+			Contenido: c.Contenido,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: toNullTime(c.UpdatedAt),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("comentario %d: %w", c.ID, err)
+		}
+		stats.NoteComments++
+	}
+
+	return stats, tx.Commit()
+}
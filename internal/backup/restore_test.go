@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// validDump devuelve una copia consistente con un usuario, un tag y dos notas
+// enlazadas.
+func validDump() *Dump {
+	contenido := "ver [[#2]]"
+	return &Dump{
+		SchemaVersion: SchemaVersion,
+		Users:         []User{{ID: 1, Username: "ana", PasswordHash: "hash"}},
+		Tags:          []Tag{{ID: 1, Nombre: "en curso"}},
+		Notes: []Note{
+			{ID: 1, Nombre: "uno", Contenido: &contenido, Version: 1, Position: "a0"},
+			{ID: 2, Nombre: "dos", Version: 3, Position: "a1"},
+		},
+		NoteTags:  []NoteTag{{NoteID: 1, TagID: 1}},
+		NoteLinks: []NoteLink{{SourceID: 1, TargetID: 2}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(validDump()); err != nil {
+		t.Fatalf("la copia valida dio error: %v", err)
+	}
+	if err := Validate(&Dump{SchemaVersion: SchemaVersion}); err != nil {
+		t.Fatalf("la copia vacia dio error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(d *Dump)
+		want   string
+	}{
+		{"usuario repetido", func(d *Dump) { d.Users = append(d.Users, User{ID: 1, Username: "bea", PasswordHash: "x"}) }, "usuario 1 repetido"},
+		{"nombre de usuario repetido", func(d *Dump) { d.Users = append(d.Users, User{ID: 2, Username: "ana", PasswordHash: "x"}) }, `usuario "ana" repetido`},
+		{"usuario sin contraseña", func(d *Dump) { d.Users[0].PasswordHash = "" }, "usuario 1 sin nombre o sin contraseña"},
+		{"tag repetido", func(d *Dump) { d.Tags = append(d.Tags, Tag{ID: 1, Nombre: "otro"}) }, "tag 1 repetido"},
+		{"nombre de tag repetido", func(d *Dump) { d.Tags = append(d.Tags, Tag{ID: 2, Nombre: "en curso"}) }, `tag "en curso" repetido`},
+		{"tag sin nombre", func(d *Dump) { d.Tags[0].Nombre = "" }, "tag 1 sin nombre"},
+		{"nota repetida", func(d *Dump) { d.Notes = append(d.Notes, Note{ID: 2, Nombre: "x", Version: 1}) }, "nota 2 repetida"},
+		{"nota sin nombre", func(d *Dump) { d.Notes[0].Nombre = "" }, "nota 1 sin nombre"},
+		{"version invalida", func(d *Dump) { d.Notes[1].Version = 0 }, "nota 2 con versión inválida 0"},
+		{"tag de nota inexistente", func(d *Dump) { d.NoteTags[0].NoteID = 9 }, "note_tags: la nota 9 no existe"},
+		{"nota con tag inexistente", func(d *Dump) { d.NoteTags[0].TagID = 9 }, "note_tags: el tag 9 no existe"},
+		{"relacion repetida", func(d *Dump) { d.NoteTags = append(d.NoteTags, d.NoteTags[0]) }, "note_tags: relación 1-1 repetida"},
+		{"enlace roto", func(d *Dump) { d.NoteLinks[0].TargetID = 9 }, "note_links: el enlace 1-9 apunta a una nota que no existe"},
+		{"enlace repetido", func(d *Dump) { d.NoteLinks = append(d.NoteLinks, d.NoteLinks[0]) }, "note_links: enlace 1-2 repetido"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := validDump()
+			tt.change(d)
+			err := Validate(d)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, se esperaba %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMaxProblems(t *testing.T) {
+	d := validDump()
+	for i := 0; i < maxProblems+5; i++ {
+		d.NoteTags = append(d.NoteTags, NoteTag{NoteID: 1, TagID: int64(100 + i)})
+	}
+	err := Validate(d)
+	if err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	// El encabezado, maxProblems problemas y el resumen del resto
+	if len(lines) != maxProblems+2 {
+		t.Errorf("se informaron %d lineas, se esperaban %d", len(lines), maxProblems+2)
+	}
+	if !strings.HasSuffix(err.Error(), "y 5 problemas más") {
+		t.Errorf("falta el resumen de los problemas restantes: %v", err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, validDump()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	d, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if err := Validate(d); err != nil {
+		t.Errorf("la copia leida no es valida: %v", err)
+	}
+	if len(d.Notes) != 2 || *d.Notes[0].Contenido != "ver [[#2]]" {
+		t.Errorf("notas leidas = %+v", d.Notes)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"version futura", `{"schema_version": 999}`},
+		{"sin version", `{}`},
+		{"campo desconocido", `{"schema_version": 1, "otro": 1}`},
+		{"JSON invalido", `{"schema_version":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.data)); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+}
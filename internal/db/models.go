@@ -6,12 +6,64 @@ package db
 
 import (
 	"database/sql"
+	"time"
 )
 
+type Attachment struct {
+	ID              int64          `json:"id"`
+	NoteID          int64          `json:"note_id"`
+	Filename        string         `json:"filename"`
+	ContentType     string         `json:"content_type"`
+	Size            int64          `json:"size"`
+	Sha256          string         `json:"sha256"`
+	ThumbnailSha256 sql.NullString `json:"thumbnail_sha256"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
 type Note struct {
+	ID          int64          `json:"id"`
+	Nombre      string         `json:"nombre"`
+	Contenido   sql.NullString `json:"contenido"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   sql.NullTime   `json:"deleted_at"`
+	Version     int64          `json:"version"`
+	PinnedAt    sql.NullTime   `json:"pinned_at"`
+	ArchivedAt  sql.NullTime   `json:"archived_at"`
+	Position    string         `json:"position"`
+	DueAt       sql.NullTime   `json:"due_at"`
+	UserID      sql.NullInt64  `json:"user_id"`
+	WorkspaceID sql.NullInt64  `json:"workspace_id"`
+}
+
+type NoteComment struct {
+	ID        int64        `json:"id"`
+	NoteID    int64        `json:"note_id"`
+	UserID    int64        `json:"user_id"`
+	Contenido string       `json:"contenido"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+type NoteLink struct {
+	SourceID int64 `json:"source_id"`
+	TargetID int64 `json:"target_id"`
+}
+
+type NoteRevision struct {
 	ID        int64          `json:"id"`
+	NoteID    int64          `json:"note_id"`
 	Nombre    string         `json:"nombre"`
 	Contenido sql.NullString `json:"contenido"`
+	EditedAt  time.Time      `json:"edited_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type NoteShare struct {
+	NoteID     int64     `json:"note_id"`
+	UserID     int64     `json:"user_id"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type NoteTag struct {
@@ -19,14 +71,63 @@ type NoteTag struct {
 	TagID  int64 `json:"tag_id"`
 }
 
+type PublicLink struct {
+	ID             int64          `json:"id"`
+	NoteID         int64          `json:"note_id"`
+	Token          string         `json:"token"`
+	PasswordHash   sql.NullString `json:"password_hash"`
+	ExpiresAt      sql.NullTime   `json:"expires_at"`
+	Views          int64          `json:"views"`
+	LastViewedAt   sql.NullTime   `json:"last_viewed_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	FailedAttempts int64          `json:"failed_attempts"`
+	LockedUntil    sql.NullTime   `json:"locked_until"`
+}
+
+type Reminder struct {
+	ID        int64          `json:"id"`
+	NoteID    int64          `json:"note_id"`
+	FireAt    time.Time      `json:"fire_at"`
+	SentAt    sql.NullTime   `json:"sent_at"`
+	ClaimedAt sql.NullTime   `json:"claimed_at"`
+	Attempts  int64          `json:"attempts"`
+	LastError sql.NullString `json:"last_error"`
+}
+
 type Tag struct {
-	ID     int64          `json:"id"`
-	Nombre string         `json:"nombre"`
-	Color  sql.NullString `json:"color"`
+	ID          int64          `json:"id"`
+	Nombre      string         `json:"nombre"`
+	Color       sql.NullString `json:"color"`
+	WorkspaceID sql.NullInt64  `json:"workspace_id"`
 }
 
 type User struct {
-	ID           int64  `json:"id"`
-	Username     string `json:"username"`
-	PasswordHash string `json:"password_hash"`
+	ID            int64          `json:"id"`
+	Username      string         `json:"username"`
+	PasswordHash  string         `json:"password_hash"`
+	CalendarToken sql.NullString `json:"calendar_token"`
+}
+
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Nombre    string    `json:"nombre"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WorkspaceInvitation struct {
+	ID          int64         `json:"id"`
+	WorkspaceID int64         `json:"workspace_id"`
+	Token       string        `json:"token"`
+	Role        string        `json:"role"`
+	CreatedBy   sql.NullInt64 `json:"created_by"`
+	Uses        int64         `json:"uses"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
 }
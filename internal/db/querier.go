@@ -6,24 +6,121 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
+	AddTagToNote(ctx context.Context, arg AddTagToNoteParams) error
+	AddWorkspaceMember(ctx context.Context, arg AddWorkspaceMemberParams) error
+	ArchiveNote(ctx context.Context, id int64) error
+	ClaimReminder(ctx context.Context, arg ClaimReminderParams) (int64, error)
+	CopyGeneralTags(ctx context.Context, workspaceID sql.NullInt64) error
+	CountAttachmentsBySHA256(ctx context.Context, sha256 string) (int64, error)
+	CountBackupRows(ctx context.Context) (CountBackupRowsRow, error)
+	CountPublicLinkView(ctx context.Context, id int64) error
+	CountWorkspaceInvitationUse(ctx context.Context, id int64) error
+	CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error)
+	CreateComment(ctx context.Context, arg CreateCommentParams) (NoteComment, error)
 	CreateNote(ctx context.Context, arg CreateNoteParams) (Note, error)
+	CreateNoteLink(ctx context.Context, arg CreateNoteLinkParams) error
+	CreateNoteRevision(ctx context.Context, arg CreateNoteRevisionParams) (NoteRevision, error)
+	CreatePublicLink(ctx context.Context, arg CreatePublicLinkParams) (PublicLink, error)
+	CreateReminder(ctx context.Context, arg CreateReminderParams) error
 	// sql/queries/query.sql
 	CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWorkspace(ctx context.Context, nombre string) (Workspace, error)
+	CreateWorkspaceInvitation(ctx context.Context, arg CreateWorkspaceInvitationParams) (WorkspaceInvitation, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+	DeleteComment(ctx context.Context, id int64) error
 	DeleteNote(ctx context.Context, id int64) error
+	DeleteNoteLinksFrom(ctx context.Context, sourceID int64) error
+	DeletePendingReminders(ctx context.Context, noteID int64) error
+	DeletePublicLink(ctx context.Context, id int64) error
+	DeleteWorkspaceInvitation(ctx context.Context, id int64) error
+	// Al llegar a @max_attempts intentos fallidos se bloquea el enlace hasta
+	// @locked_until y se vuelve a contar desde cero.
+	FailPublicLinkPassword(ctx context.Context, arg FailPublicLinkPasswordParams) error
+	GetAttachment(ctx context.Context, id int64) (Attachment, error)
+	GetComment(ctx context.Context, id int64) (NoteComment, error)
+	GetFirstNotePosition(ctx context.Context, id int64) (string, error)
+	GetNextNoteRevision(ctx context.Context, arg GetNextNoteRevisionParams) (NoteRevision, error)
 	GetNote(ctx context.Context, id int64) (Note, error)
+	GetNoteByName(ctx context.Context, arg GetNoteByNameParams) (Note, error)
+	GetNoteRevision(ctx context.Context, id int64) (NoteRevision, error)
+	GetNoteShare(ctx context.Context, arg GetNoteShareParams) (NoteShare, error)
+	GetPublicLink(ctx context.Context, id int64) (PublicLink, error)
+	GetPublicLinkByToken(ctx context.Context, token string) (PublicLink, error)
 	GetTag(ctx context.Context, id int64) (Tag, error)
 	GetTagsForNote(ctx context.Context, noteID int64) ([]Tag, error)
+	GetUser(ctx context.Context, id int64) (User, error)
+	GetUserByCalendarToken(ctx context.Context, calendarToken sql.NullString) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetWorkspace(ctx context.Context, id int64) (Workspace, error)
+	GetWorkspaceInvitation(ctx context.Context, id int64) (WorkspaceInvitation, error)
+	GetWorkspaceInvitationByToken(ctx context.Context, token string) (WorkspaceInvitation, error)
+	GetWorkspaceMember(ctx context.Context, arg GetWorkspaceMemberParams) (WorkspaceMember, error)
+	InsertBackupComment(ctx context.Context, arg InsertBackupCommentParams) error
+	InsertBackupNote(ctx context.Context, arg InsertBackupNoteParams) (int64, error)
+	InsertBackupNoteShare(ctx context.Context, arg InsertBackupNoteShareParams) error
+	InsertBackupUser(ctx context.Context, arg InsertBackupUserParams) (int64, error)
+	InsertBackupWorkspace(ctx context.Context, arg InsertBackupWorkspaceParams) (int64, error)
+	InsertBackupWorkspaceMember(ctx context.Context, arg InsertBackupWorkspaceMemberParams) error
 	LinkTagToNote(ctx context.Context, arg LinkTagToNoteParams) error
+	ListAllBacklinks(ctx context.Context, arg ListAllBacklinksParams) ([]ListAllBacklinksRow, error)
+	ListAllComments(ctx context.Context) ([]NoteComment, error)
+	ListAllNotes(ctx context.Context) ([]Note, error)
+	ListAllTags(ctx context.Context) ([]Tag, error)
+	ListAllWorkspaceMembers(ctx context.Context) ([]WorkspaceMember, error)
+	ListAllWorkspaces(ctx context.Context) ([]Workspace, error)
+	ListAttachments(ctx context.Context, arg ListAttachmentsParams) ([]Attachment, error)
+	ListAttachmentsForNote(ctx context.Context, noteID int64) ([]Attachment, error)
+	ListBacklinks(ctx context.Context, targetID int64) ([]Note, error)
+	ListCommentsForNote(ctx context.Context, noteID int64) ([]ListCommentsForNoteRow, error)
+	ListDueReminders(ctx context.Context, arg ListDueRemindersParams) ([]ListDueRemindersRow, error)
+	ListNoteLinks(ctx context.Context) ([]NoteLink, error)
+	ListNoteRevisions(ctx context.Context, noteID int64) ([]NoteRevision, error)
+	ListNoteShares(ctx context.Context) ([]NoteShare, error)
+	ListNoteTags(ctx context.Context) ([]NoteTag, error)
 	ListNotes(ctx context.Context) ([]Note, error)
-	ListNotesWithTags(ctx context.Context) ([]ListNotesWithTagsRow, error)
-	ListTags(ctx context.Context) ([]Tag, error)
+	ListNotesMentioning(ctx context.Context, arg ListNotesMentioningParams) ([]Note, error)
+	ListNotesSharedWith(ctx context.Context, userID int64) ([]ListNotesSharedWithRow, error)
+	ListNotesWithTags(ctx context.Context, arg ListNotesWithTagsParams) ([]ListNotesWithTagsRow, error)
+	ListPublicLinksForNote(ctx context.Context, noteID int64) ([]PublicLink, error)
+	ListPurgeableAttachmentHashes(ctx context.Context, cutoff sql.NullTime) ([]string, error)
+	ListSharesForNote(ctx context.Context, noteID int64) ([]ListSharesForNoteRow, error)
+	ListTags(ctx context.Context, workspaceID sql.NullInt64) ([]Tag, error)
+	ListTrashedNotes(ctx context.Context, arg ListTrashedNotesParams) ([]Note, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	ListWorkspaceInvitations(ctx context.Context, workspaceID int64) ([]WorkspaceInvitation, error)
+	ListWorkspaceMembers(ctx context.Context, workspaceID int64) ([]ListWorkspaceMembersRow, error)
+	ListWorkspacesForUser(ctx context.Context, userID int64) ([]ListWorkspacesForUserRow, error)
+	MarkReminderSent(ctx context.Context, arg MarkReminderSentParams) error
+	MoveNote(ctx context.Context, arg MoveNoteParams) error
+	PinNote(ctx context.Context, id int64) error
+	PurgeTrashedNotes(ctx context.Context, cutoff sql.NullTime) (int64, error)
+	ReleaseReminder(ctx context.Context, arg ReleaseReminderParams) error
+	// Igual que SetWorkspaceMemberRole, no se quita al ultimo administrador.
+	RemoveWorkspaceMember(ctx context.Context, arg RemoveWorkspaceMemberParams) (int64, error)
+	ResetPublicLinkAttempts(ctx context.Context, id int64) error
+	RestoreNote(ctx context.Context, id int64) error
+	RewriteNoteContent(ctx context.Context, arg RewriteNoteContentParams) error
+	SetBackupNoteContent(ctx context.Context, arg SetBackupNoteContentParams) error
+	SetNoteDueAt(ctx context.Context, arg SetNoteDueAtParams) error
+	SetUserCalendarToken(ctx context.Context, arg SetUserCalendarTokenParams) error
+	// Un administrador solo deja de serlo si queda otro en el espacio. La cuenta
+	// se hace en la misma sentencia para que dos cambios simultaneos no dejen el
+	// espacio sin administradores.
+	SetWorkspaceMemberRole(ctx context.Context, arg SetWorkspaceMemberRoleParams) (int64, error)
+	ShareNote(ctx context.Context, arg ShareNoteParams) error
+	TrashNote(ctx context.Context, id int64) error
+	UnarchiveNote(ctx context.Context, id int64) error
+	UnlinkTagFromNote(ctx context.Context, arg UnlinkTagFromNoteParams) error
 	UnlinkTagsFromNote(ctx context.Context, noteID int64) error
-	UpdateNote(ctx context.Context, arg UpdateNoteParams) error
+	UnpinNote(ctx context.Context, id int64) error
+	UnshareNote(ctx context.Context, arg UnshareNoteParams) (int64, error)
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (NoteComment, error)
+	UpdateNote(ctx context.Context, arg UpdateNoteParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
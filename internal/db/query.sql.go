@@ -8,50 +8,378 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
-const createNote = `-- name: CreateNote :one
-INSERT INTO notes (nombre, contenido)
+const addTagToNote = `-- name: AddTagToNote :exec
+INSERT OR IGNORE INTO note_tags (note_id, tag_id)
 VALUES (?, ?)
-RETURNING id, nombre, contenido
+`
+
+type AddTagToNoteParams struct {
+	NoteID int64 `json:"note_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+func (q *Queries) AddTagToNote(ctx context.Context, arg AddTagToNoteParams) error {
+	_, err := q.db.ExecContext(ctx, addTagToNote, arg.NoteID, arg.TagID)
+	return err
+}
+
+const addWorkspaceMember = `-- name: AddWorkspaceMember :exec
+INSERT INTO workspace_members (workspace_id, user_id, role)
+VALUES (?, ?, ?)
+ON CONFLICT (workspace_id, user_id) DO NOTHING
+`
+
+type AddWorkspaceMemberParams struct {
+	WorkspaceID int64  `json:"workspace_id"`
+	UserID      int64  `json:"user_id"`
+	Role        string `json:"role"`
+}
+
+func (q *Queries) AddWorkspaceMember(ctx context.Context, arg AddWorkspaceMemberParams) error {
+	_, err := q.db.ExecContext(ctx, addWorkspaceMember, arg.WorkspaceID, arg.UserID, arg.Role)
+	return err
+}
+
+const archiveNote = `-- name: ArchiveNote :exec
+UPDATE notes
+SET archived_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL AND archived_at IS NULL
+`
+
+func (q *Queries) ArchiveNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, archiveNote, id)
+	return err
+}
+
+const claimReminder = `-- name: ClaimReminder :execrows
+UPDATE reminders
+SET claimed_at = ?1
+WHERE id = ?2 AND sent_at IS NULL
+  AND (claimed_at IS NULL OR claimed_at <= ?3)
+`
+
+type ClaimReminderParams struct {
+	ClaimedAt sql.NullTime `json:"claimed_at"`
+	ID        int64        `json:"id"`
+	Stale     sql.NullTime `json:"stale"`
+}
+
+func (q *Queries) ClaimReminder(ctx context.Context, arg ClaimReminderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimReminder, arg.ClaimedAt, arg.ID, arg.Stale)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const copyGeneralTags = `-- name: CopyGeneralTags :exec
+INSERT INTO tags (nombre, color, workspace_id)
+SELECT nombre, color, ?1 FROM tags
+WHERE workspace_id IS NULL
+`
+
+func (q *Queries) CopyGeneralTags(ctx context.Context, workspaceID sql.NullInt64) error {
+	_, err := q.db.ExecContext(ctx, copyGeneralTags, workspaceID)
+	return err
+}
+
+const countAttachmentsBySHA256 = `-- name: CountAttachmentsBySHA256 :one
+SELECT COUNT(*) FROM attachments
+WHERE sha256 = ?1 OR thumbnail_sha256 = ?1
+`
+
+func (q *Queries) CountAttachmentsBySHA256(ctx context.Context, sha256 string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAttachmentsBySHA256, sha256)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBackupRows = `-- name: CountBackupRows :one
+SELECT
+    (SELECT COUNT(*) FROM users) AS users,
+    (SELECT COUNT(*) FROM notes) AS notes,
+    (SELECT COUNT(*) FROM tags) AS tags,
+    (SELECT COUNT(*) FROM workspaces) AS workspaces
+`
+
+type CountBackupRowsRow struct {
+	Users      int64 `json:"users"`
+	Notes      int64 `json:"notes"`
+	Tags       int64 `json:"tags"`
+	Workspaces int64 `json:"workspaces"`
+}
+
+func (q *Queries) CountBackupRows(ctx context.Context) (CountBackupRowsRow, error) {
+	row := q.db.QueryRowContext(ctx, countBackupRows)
+	var i CountBackupRowsRow
+	err := row.Scan(
+		&i.Users,
+		&i.Notes,
+		&i.Tags,
+		&i.Workspaces,
+	)
+	return i, err
+}
+
+const countPublicLinkView = `-- name: CountPublicLinkView :exec
+UPDATE public_links
+SET views = views + 1, last_viewed_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+func (q *Queries) CountPublicLinkView(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, countPublicLinkView, id)
+	return err
+}
+
+const countWorkspaceInvitationUse = `-- name: CountWorkspaceInvitationUse :exec
+UPDATE workspace_invitations
+SET uses = uses + 1
+WHERE id = ?
+`
+
+func (q *Queries) CountWorkspaceInvitationUse(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, countWorkspaceInvitationUse, id)
+	return err
+}
+
+const createAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments (note_id, filename, content_type, size, sha256, thumbnail_sha256)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, note_id, filename, content_type, size, sha256, thumbnail_sha256, created_at
+`
+
+type CreateAttachmentParams struct {
+	NoteID          int64          `json:"note_id"`
+	Filename        string         `json:"filename"`
+	ContentType     string         `json:"content_type"`
+	Size            int64          `json:"size"`
+	Sha256          string         `json:"sha256"`
+	ThumbnailSha256 sql.NullString `json:"thumbnail_sha256"`
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRowContext(ctx, createAttachment,
+		arg.NoteID,
+		arg.Filename,
+		arg.ContentType,
+		arg.Size,
+		arg.Sha256,
+		arg.ThumbnailSha256,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Sha256,
+		&i.ThumbnailSha256,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createComment = `-- name: CreateComment :one
+INSERT INTO note_comments (note_id, user_id, contenido)
+VALUES (?, ?, ?)
+RETURNING id, note_id, user_id, contenido, created_at, updated_at
+`
+
+type CreateCommentParams struct {
+	NoteID    int64  `json:"note_id"`
+	UserID    int64  `json:"user_id"`
+	Contenido string `json:"contenido"`
+}
+
+func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (NoteComment, error) {
+	row := q.db.QueryRowContext(ctx, createComment, arg.NoteID, arg.UserID, arg.Contenido)
+	var i NoteComment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createNote = `-- name: CreateNote :one
+INSERT INTO notes (nombre, contenido, position, user_id, workspace_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id
 `
 
 type CreateNoteParams struct {
-	Nombre    string         `json:"nombre"`
-	Contenido sql.NullString `json:"contenido"`
+	Nombre      string         `json:"nombre"`
+	Contenido   sql.NullString `json:"contenido"`
+	Position    string         `json:"position"`
+	UserID      sql.NullInt64  `json:"user_id"`
+	WorkspaceID sql.NullInt64  `json:"workspace_id"`
 }
 
 func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (Note, error) {
-	row := q.db.QueryRowContext(ctx, createNote, arg.Nombre, arg.Contenido)
+	row := q.db.QueryRowContext(ctx, createNote,
+		arg.Nombre,
+		arg.Contenido,
+		arg.Position,
+		arg.UserID,
+		arg.WorkspaceID,
+	)
 	var i Note
-	err := row.Scan(&i.ID, &i.Nombre, &i.Contenido)
+	err := row.Scan(
+		&i.ID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.PinnedAt,
+		&i.ArchivedAt,
+		&i.Position,
+		&i.DueAt,
+		&i.UserID,
+		&i.WorkspaceID,
+	)
 	return i, err
 }
 
-const createTag = `-- name: CreateTag :one
+const createNoteLink = `-- name: CreateNoteLink :exec
+INSERT OR IGNORE INTO note_links (source_id, target_id)
+VALUES (?, ?)
+`
+
+type CreateNoteLinkParams struct {
+	SourceID int64 `json:"source_id"`
+	TargetID int64 `json:"target_id"`
+}
+
+func (q *Queries) CreateNoteLink(ctx context.Context, arg CreateNoteLinkParams) error {
+	_, err := q.db.ExecContext(ctx, createNoteLink, arg.SourceID, arg.TargetID)
+	return err
+}
+
+const createNoteRevision = `-- name: CreateNoteRevision :one
+INSERT INTO note_revisions (note_id, nombre, contenido, edited_at)
+VALUES (?, ?, ?, ?)
+RETURNING id, note_id, nombre, contenido, edited_at, created_at
+`
+
+type CreateNoteRevisionParams struct {
+	NoteID    int64          `json:"note_id"`
+	Nombre    string         `json:"nombre"`
+	Contenido sql.NullString `json:"contenido"`
+	EditedAt  time.Time      `json:"edited_at"`
+}
+
+func (q *Queries) CreateNoteRevision(ctx context.Context, arg CreateNoteRevisionParams) (NoteRevision, error) {
+	row := q.db.QueryRowContext(ctx, createNoteRevision,
+		arg.NoteID,
+		arg.Nombre,
+		arg.Contenido,
+		arg.EditedAt,
+	)
+	var i NoteRevision
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.EditedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPublicLink = `-- name: CreatePublicLink :one
+INSERT INTO public_links (note_id, token, password_hash, expires_at)
+VALUES (?, ?, ?, ?)
+RETURNING id, note_id, token, password_hash, expires_at, views, last_viewed_at, created_at, failed_attempts, locked_until
+`
 
-INSERT INTO tags (nombre, color)
+type CreatePublicLinkParams struct {
+	NoteID       int64          `json:"note_id"`
+	Token        string         `json:"token"`
+	PasswordHash sql.NullString `json:"password_hash"`
+	ExpiresAt    sql.NullTime   `json:"expires_at"`
+}
+
+func (q *Queries) CreatePublicLink(ctx context.Context, arg CreatePublicLinkParams) (PublicLink, error) {
+	row := q.db.QueryRowContext(ctx, createPublicLink,
+		arg.NoteID,
+		arg.Token,
+		arg.PasswordHash,
+		arg.ExpiresAt,
+	)
+	var i PublicLink
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Token,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.Views,
+		&i.LastViewedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const createReminder = `-- name: CreateReminder :exec
+INSERT OR IGNORE INTO reminders (note_id, fire_at)
 VALUES (?, ?)
-RETURNING id, nombre, color
+`
+
+type CreateReminderParams struct {
+	NoteID int64     `json:"note_id"`
+	FireAt time.Time `json:"fire_at"`
+}
+
+func (q *Queries) CreateReminder(ctx context.Context, arg CreateReminderParams) error {
+	_, err := q.db.ExecContext(ctx, createReminder, arg.NoteID, arg.FireAt)
+	return err
+}
+
+const createTag = `-- name: CreateTag :one
+
+INSERT INTO tags (nombre, color, workspace_id)
+VALUES (?, ?, ?)
+RETURNING id, nombre, color, workspace_id
 `
 
 type CreateTagParams struct {
-	Nombre string         `json:"nombre"`
-	Color  sql.NullString `json:"color"`
+	Nombre      string         `json:"nombre"`
+	Color       sql.NullString `json:"color"`
+	WorkspaceID sql.NullInt64  `json:"workspace_id"`
 }
 
 // sql/queries/query.sql
 func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
-	row := q.db.QueryRowContext(ctx, createTag, arg.Nombre, arg.Color)
+	row := q.db.QueryRowContext(ctx, createTag, arg.Nombre, arg.Color, arg.WorkspaceID)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Nombre, &i.Color)
+	err := row.Scan(
+		&i.ID,
+		&i.Nombre,
+		&i.Color,
+		&i.WorkspaceID,
+	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, password_hash)
 VALUES (?, ?)
-RETURNING id, username, password_hash
+RETURNING id, username, password_hash, calendar_token
 `
 
 type CreateUserParams struct {
@@ -62,10 +390,84 @@ type CreateUserParams struct {
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, createUser, arg.Username, arg.PasswordHash)
 	var i User
-	err := row.Scan(&i.ID, &i.Username, &i.PasswordHash)
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CalendarToken,
+	)
+	return i, err
+}
+
+const createWorkspace = `-- name: CreateWorkspace :one
+INSERT INTO workspaces (nombre)
+VALUES (?)
+RETURNING id, nombre, created_at
+`
+
+func (q *Queries) CreateWorkspace(ctx context.Context, nombre string) (Workspace, error) {
+	row := q.db.QueryRowContext(ctx, createWorkspace, nombre)
+	var i Workspace
+	err := row.Scan(&i.ID, &i.Nombre, &i.CreatedAt)
+	return i, err
+}
+
+const createWorkspaceInvitation = `-- name: CreateWorkspaceInvitation :one
+INSERT INTO workspace_invitations (workspace_id, token, role, created_by, expires_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id, workspace_id, token, role, created_by, uses, expires_at, created_at
+`
+
+type CreateWorkspaceInvitationParams struct {
+	WorkspaceID int64         `json:"workspace_id"`
+	Token       string        `json:"token"`
+	Role        string        `json:"role"`
+	CreatedBy   sql.NullInt64 `json:"created_by"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+}
+
+func (q *Queries) CreateWorkspaceInvitation(ctx context.Context, arg CreateWorkspaceInvitationParams) (WorkspaceInvitation, error) {
+	row := q.db.QueryRowContext(ctx, createWorkspaceInvitation,
+		arg.WorkspaceID,
+		arg.Token,
+		arg.Role,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+	)
+	var i WorkspaceInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.WorkspaceID,
+		&i.Token,
+		&i.Role,
+		&i.CreatedBy,
+		&i.Uses,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
 	return i, err
 }
 
+const deleteAttachment = `-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = ?
+`
+
+func (q *Queries) DeleteAttachment(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAttachment, id)
+	return err
+}
+
+const deleteComment = `-- name: DeleteComment :exec
+DELETE FROM note_comments
+WHERE id = ?
+`
+
+func (q *Queries) DeleteComment(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteComment, id)
+	return err
+}
+
 const deleteNote = `-- name: DeleteNote :exec
 DELETE FROM notes
 WHERE id = ?
@@ -76,32 +478,313 @@ func (q *Queries) DeleteNote(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteNoteLinksFrom = `-- name: DeleteNoteLinksFrom :exec
+DELETE FROM note_links
+WHERE source_id = ?
+`
+
+func (q *Queries) DeleteNoteLinksFrom(ctx context.Context, sourceID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteNoteLinksFrom, sourceID)
+	return err
+}
+
+const deletePendingReminders = `-- name: DeletePendingReminders :exec
+DELETE FROM reminders
+WHERE note_id = ? AND sent_at IS NULL
+`
+
+func (q *Queries) DeletePendingReminders(ctx context.Context, noteID int64) error {
+	_, err := q.db.ExecContext(ctx, deletePendingReminders, noteID)
+	return err
+}
+
+const deletePublicLink = `-- name: DeletePublicLink :exec
+DELETE FROM public_links
+WHERE id = ?
+`
+
+func (q *Queries) DeletePublicLink(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deletePublicLink, id)
+	return err
+}
+
+const deleteWorkspaceInvitation = `-- name: DeleteWorkspaceInvitation :exec
+DELETE FROM workspace_invitations
+WHERE id = ?
+`
+
+func (q *Queries) DeleteWorkspaceInvitation(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWorkspaceInvitation, id)
+	return err
+}
+
+const failPublicLinkPassword = `-- name: FailPublicLinkPassword :exec
+UPDATE public_links
+SET failed_attempts = CASE WHEN failed_attempts + 1 >= ?1 THEN 0 ELSE failed_attempts + 1 END,
+    locked_until = CASE WHEN failed_attempts + 1 >= ?1 THEN ?2 ELSE locked_until END
+WHERE id = ?3
+`
+
+type FailPublicLinkPasswordParams struct {
+	MaxAttempts int64        `json:"max_attempts"`
+	LockedUntil sql.NullTime `json:"locked_until"`
+	ID          int64        `json:"id"`
+}
+
+// Al llegar a @max_attempts intentos fallidos se bloquea el enlace hasta
+// @locked_until y se vuelve a contar desde cero.
+func (q *Queries) FailPublicLinkPassword(ctx context.Context, arg FailPublicLinkPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, failPublicLinkPassword, arg.MaxAttempts, arg.LockedUntil, arg.ID)
+	return err
+}
+
+const getAttachment = `-- name: GetAttachment :one
+SELECT id, note_id, filename, content_type, size, sha256, thumbnail_sha256, created_at FROM attachments
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetAttachment(ctx context.Context, id int64) (Attachment, error) {
+	row := q.db.QueryRowContext(ctx, getAttachment, id)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Filename,
+		&i.ContentType,
+		&i.Size,
+		&i.Sha256,
+		&i.ThumbnailSha256,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getComment = `-- name: GetComment :one
+SELECT id, note_id, user_id, contenido, created_at, updated_at FROM note_comments
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetComment(ctx context.Context, id int64) (NoteComment, error) {
+	row := q.db.QueryRowContext(ctx, getComment, id)
+	var i NoteComment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getFirstNotePosition = `-- name: GetFirstNotePosition :one
+SELECT position FROM notes
+WHERE id != ?
+ORDER BY position
+LIMIT 1
+`
+
+func (q *Queries) GetFirstNotePosition(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getFirstNotePosition, id)
+	var position string
+	err := row.Scan(&position)
+	return position, err
+}
+
+const getNextNoteRevision = `-- name: GetNextNoteRevision :one
+SELECT id, note_id, nombre, contenido, edited_at, created_at FROM note_revisions
+WHERE note_id = ? AND id > ?
+ORDER BY id
+LIMIT 1
+`
+
+type GetNextNoteRevisionParams struct {
+	NoteID int64 `json:"note_id"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) GetNextNoteRevision(ctx context.Context, arg GetNextNoteRevisionParams) (NoteRevision, error) {
+	row := q.db.QueryRowContext(ctx, getNextNoteRevision, arg.NoteID, arg.ID)
+	var i NoteRevision
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.EditedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getNote = `-- name: GetNote :one
-SELECT id, nombre, contenido FROM notes
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
 WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetNote(ctx context.Context, id int64) (Note, error) {
 	row := q.db.QueryRowContext(ctx, getNote, id)
 	var i Note
-	err := row.Scan(&i.ID, &i.Nombre, &i.Contenido)
+	err := row.Scan(
+		&i.ID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.PinnedAt,
+		&i.ArchivedAt,
+		&i.Position,
+		&i.DueAt,
+		&i.UserID,
+		&i.WorkspaceID,
+	)
+	return i, err
+}
+
+const getNoteByName = `-- name: GetNoteByName :one
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
+WHERE workspace_id IS ?1 AND (workspace_id IS NOT NULL OR user_id = ?2)
+    AND nombre = ?3 COLLATE NOCASE AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1
+`
+
+type GetNoteByNameParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+	Nombre      string        `json:"nombre"`
+}
+
+func (q *Queries) GetNoteByName(ctx context.Context, arg GetNoteByNameParams) (Note, error) {
+	row := q.db.QueryRowContext(ctx, getNoteByName, arg.WorkspaceID, arg.UserID, arg.Nombre)
+	var i Note
+	err := row.Scan(
+		&i.ID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.PinnedAt,
+		&i.ArchivedAt,
+		&i.Position,
+		&i.DueAt,
+		&i.UserID,
+		&i.WorkspaceID,
+	)
+	return i, err
+}
+
+const getNoteRevision = `-- name: GetNoteRevision :one
+SELECT id, note_id, nombre, contenido, edited_at, created_at FROM note_revisions
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetNoteRevision(ctx context.Context, id int64) (NoteRevision, error) {
+	row := q.db.QueryRowContext(ctx, getNoteRevision, id)
+	var i NoteRevision
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Nombre,
+		&i.Contenido,
+		&i.EditedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getNoteShare = `-- name: GetNoteShare :one
+SELECT note_id, user_id, permission, created_at FROM note_shares
+WHERE note_id = ? AND user_id = ?
+`
+
+type GetNoteShareParams struct {
+	NoteID int64 `json:"note_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetNoteShare(ctx context.Context, arg GetNoteShareParams) (NoteShare, error) {
+	row := q.db.QueryRowContext(ctx, getNoteShare, arg.NoteID, arg.UserID)
+	var i NoteShare
+	err := row.Scan(
+		&i.NoteID,
+		&i.UserID,
+		&i.Permission,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPublicLink = `-- name: GetPublicLink :one
+SELECT id, note_id, token, password_hash, expires_at, views, last_viewed_at, created_at, failed_attempts, locked_until FROM public_links
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetPublicLink(ctx context.Context, id int64) (PublicLink, error) {
+	row := q.db.QueryRowContext(ctx, getPublicLink, id)
+	var i PublicLink
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Token,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.Views,
+		&i.LastViewedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getPublicLinkByToken = `-- name: GetPublicLinkByToken :one
+SELECT id, note_id, token, password_hash, expires_at, views, last_viewed_at, created_at, failed_attempts, locked_until FROM public_links
+WHERE token = ? LIMIT 1
+`
+
+func (q *Queries) GetPublicLinkByToken(ctx context.Context, token string) (PublicLink, error) {
+	row := q.db.QueryRowContext(ctx, getPublicLinkByToken, token)
+	var i PublicLink
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.Token,
+		&i.PasswordHash,
+		&i.ExpiresAt,
+		&i.Views,
+		&i.LastViewedAt,
+		&i.CreatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
 	return i, err
 }
 
 const getTag = `-- name: GetTag :one
-SELECT id, nombre, color FROM tags
+SELECT id, nombre, color, workspace_id FROM tags
 WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetTag(ctx context.Context, id int64) (Tag, error) {
 	row := q.db.QueryRowContext(ctx, getTag, id)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Nombre, &i.Color)
+	err := row.Scan(
+		&i.ID,
+		&i.Nombre,
+		&i.Color,
+		&i.WorkspaceID,
+	)
 	return i, err
 }
 
 const getTagsForNote = `-- name: GetTagsForNote :many
-SELECT t.id, t.nombre, t.color FROM tags t
+SELECT t.id, t.nombre, t.color, t.workspace_id FROM tags t
 JOIN note_tags nt ON t.id = nt.tag_id
 WHERE nt.note_id = ?
 `
@@ -115,7 +798,12 @@ func (q *Queries) GetTagsForNote(ctx context.Context, noteID int64) ([]Tag, erro
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Nombre, &i.Color); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Color,
+			&i.WorkspaceID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -129,48 +817,1279 @@ func (q *Queries) GetTagsForNote(ctx context.Context, noteID int64) ([]Tag, erro
 	return items, nil
 }
 
+const getUser = `-- name: GetUser :one
+SELECT id, username, password_hash, calendar_token FROM users
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CalendarToken,
+	)
+	return i, err
+}
+
+const getUserByCalendarToken = `-- name: GetUserByCalendarToken :one
+SELECT id, username, password_hash, calendar_token FROM users
+WHERE calendar_token = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByCalendarToken(ctx context.Context, calendarToken sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByCalendarToken, calendarToken)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CalendarToken,
+	)
+	return i, err
+}
+
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, password_hash FROM users
+SELECT id, username, password_hash, calendar_token FROM users
 WHERE username = ? LIMIT 1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUserByUsername, username)
 	var i User
-	err := row.Scan(&i.ID, &i.Username, &i.PasswordHash)
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CalendarToken,
+	)
 	return i, err
 }
 
-const linkTagToNote = `-- name: LinkTagToNote :exec
-INSERT INTO note_tags (note_id, tag_id)
-VALUES (?, ?)
+const getWorkspace = `-- name: GetWorkspace :one
+SELECT id, nombre, created_at FROM workspaces
+WHERE id = ? LIMIT 1
 `
 
-type LinkTagToNoteParams struct {
-	NoteID int64 `json:"note_id"`
-	TagID  int64 `json:"tag_id"`
+func (q *Queries) GetWorkspace(ctx context.Context, id int64) (Workspace, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspace, id)
+	var i Workspace
+	err := row.Scan(&i.ID, &i.Nombre, &i.CreatedAt)
+	return i, err
 }
 
-func (q *Queries) LinkTagToNote(ctx context.Context, arg LinkTagToNoteParams) error {
-	_, err := q.db.ExecContext(ctx, linkTagToNote, arg.NoteID, arg.TagID)
-	return err
+const getWorkspaceInvitation = `-- name: GetWorkspaceInvitation :one
+SELECT id, workspace_id, token, role, created_by, uses, expires_at, created_at FROM workspace_invitations
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetWorkspaceInvitation(ctx context.Context, id int64) (WorkspaceInvitation, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceInvitation, id)
+	var i WorkspaceInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.WorkspaceID,
+		&i.Token,
+		&i.Role,
+		&i.CreatedBy,
+		&i.Uses,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
 }
 
-const listNotes = `-- name: ListNotes :many
-SELECT id, nombre, contenido FROM notes
-ORDER BY id DESC
+const getWorkspaceInvitationByToken = `-- name: GetWorkspaceInvitationByToken :one
+SELECT id, workspace_id, token, role, created_by, uses, expires_at, created_at FROM workspace_invitations
+WHERE token = ? LIMIT 1
 `
 
-func (q *Queries) ListNotes(ctx context.Context) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, listNotes)
-	if err != nil {
-		return nil, err
+func (q *Queries) GetWorkspaceInvitationByToken(ctx context.Context, token string) (WorkspaceInvitation, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceInvitationByToken, token)
+	var i WorkspaceInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.WorkspaceID,
+		&i.Token,
+		&i.Role,
+		&i.CreatedBy,
+		&i.Uses,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWorkspaceMember = `-- name: GetWorkspaceMember :one
+SELECT workspace_id, user_id, role, created_at FROM workspace_members
+WHERE workspace_id = ? AND user_id = ?
+`
+
+type GetWorkspaceMemberParams struct {
+	WorkspaceID int64 `json:"workspace_id"`
+	UserID      int64 `json:"user_id"`
+}
+
+func (q *Queries) GetWorkspaceMember(ctx context.Context, arg GetWorkspaceMemberParams) (WorkspaceMember, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceMember, arg.WorkspaceID, arg.UserID)
+	var i WorkspaceMember
+	err := row.Scan(
+		&i.WorkspaceID,
+		&i.UserID,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertBackupComment = `-- name: InsertBackupComment :exec
+INSERT INTO note_comments (note_id, user_id, contenido, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type InsertBackupCommentParams struct {
+	NoteID    int64        `json:"note_id"`
+	UserID    int64        `json:"user_id"`
+	Contenido string       `json:"contenido"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) InsertBackupComment(ctx context.Context, arg InsertBackupCommentParams) error {
+	_, err := q.db.ExecContext(ctx, insertBackupComment,
+		arg.NoteID,
+		arg.UserID,
+		arg.Contenido,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const insertBackupNote = `-- name: InsertBackupNote :one
+INSERT INTO notes (nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id
+`
+
+type InsertBackupNoteParams struct {
+	Nombre      string         `json:"nombre"`
+	Contenido   sql.NullString `json:"contenido"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   sql.NullTime   `json:"deleted_at"`
+	Version     int64          `json:"version"`
+	PinnedAt    sql.NullTime   `json:"pinned_at"`
+	ArchivedAt  sql.NullTime   `json:"archived_at"`
+	Position    string         `json:"position"`
+	DueAt       sql.NullTime   `json:"due_at"`
+	UserID      sql.NullInt64  `json:"user_id"`
+	WorkspaceID sql.NullInt64  `json:"workspace_id"`
+}
+
+func (q *Queries) InsertBackupNote(ctx context.Context, arg InsertBackupNoteParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertBackupNote,
+		arg.Nombre,
+		arg.Contenido,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.DeletedAt,
+		arg.Version,
+		arg.PinnedAt,
+		arg.ArchivedAt,
+		arg.Position,
+		arg.DueAt,
+		arg.UserID,
+		arg.WorkspaceID,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertBackupNoteShare = `-- name: InsertBackupNoteShare :exec
+INSERT INTO note_shares (note_id, user_id, permission, created_at)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertBackupNoteShareParams struct {
+	NoteID     int64     `json:"note_id"`
+	UserID     int64     `json:"user_id"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (q *Queries) InsertBackupNoteShare(ctx context.Context, arg InsertBackupNoteShareParams) error {
+	_, err := q.db.ExecContext(ctx, insertBackupNoteShare,
+		arg.NoteID,
+		arg.UserID,
+		arg.Permission,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertBackupUser = `-- name: InsertBackupUser :one
+INSERT INTO users (username, password_hash, calendar_token)
+VALUES (?, ?, ?)
+RETURNING id
+`
+
+type InsertBackupUserParams struct {
+	Username      string         `json:"username"`
+	PasswordHash  string         `json:"password_hash"`
+	CalendarToken sql.NullString `json:"calendar_token"`
+}
+
+func (q *Queries) InsertBackupUser(ctx context.Context, arg InsertBackupUserParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertBackupUser, arg.Username, arg.PasswordHash, arg.CalendarToken)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertBackupWorkspace = `-- name: InsertBackupWorkspace :one
+INSERT INTO workspaces (nombre, created_at)
+VALUES (?, ?)
+RETURNING id
+`
+
+type InsertBackupWorkspaceParams struct {
+	Nombre    string    `json:"nombre"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) InsertBackupWorkspace(ctx context.Context, arg InsertBackupWorkspaceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertBackupWorkspace, arg.Nombre, arg.CreatedAt)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertBackupWorkspaceMember = `-- name: InsertBackupWorkspaceMember :exec
+INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertBackupWorkspaceMemberParams struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	UserID      int64     `json:"user_id"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (q *Queries) InsertBackupWorkspaceMember(ctx context.Context, arg InsertBackupWorkspaceMemberParams) error {
+	_, err := q.db.ExecContext(ctx, insertBackupWorkspaceMember,
+		arg.WorkspaceID,
+		arg.UserID,
+		arg.Role,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const linkTagToNote = `-- name: LinkTagToNote :exec
+INSERT INTO note_tags (note_id, tag_id)
+VALUES (?, ?)
+`
+
+type LinkTagToNoteParams struct {
+	NoteID int64 `json:"note_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+func (q *Queries) LinkTagToNote(ctx context.Context, arg LinkTagToNoteParams) error {
+	_, err := q.db.ExecContext(ctx, linkTagToNote, arg.NoteID, arg.TagID)
+	return err
+}
+
+const listAllBacklinks = `-- name: ListAllBacklinks :many
+SELECT l.target_id, n.id AS source_id, n.nombre AS source_nombre
+FROM note_links l
+JOIN notes n ON n.id = l.source_id
+WHERE n.deleted_at IS NULL
+    AND n.workspace_id IS ?1
+    AND (n.workspace_id IS NOT NULL OR n.user_id = ?2)
+ORDER BY n.nombre
+`
+
+type ListAllBacklinksParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+}
+
+type ListAllBacklinksRow struct {
+	TargetID     int64  `json:"target_id"`
+	SourceID     int64  `json:"source_id"`
+	SourceNombre string `json:"source_nombre"`
+}
+
+func (q *Queries) ListAllBacklinks(ctx context.Context, arg ListAllBacklinksParams) ([]ListAllBacklinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAllBacklinks, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAllBacklinksRow
+	for rows.Next() {
+		var i ListAllBacklinksRow
+		if err := rows.Scan(&i.TargetID, &i.SourceID, &i.SourceNombre); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllComments = `-- name: ListAllComments :many
+SELECT id, note_id, user_id, contenido, created_at, updated_at FROM note_comments
+ORDER BY id
+`
+
+func (q *Queries) ListAllComments(ctx context.Context) ([]NoteComment, error) {
+	rows, err := q.db.QueryContext(ctx, listAllComments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NoteComment
+	for rows.Next() {
+		var i NoteComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllNotes = `-- name: ListAllNotes :many
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
+ORDER BY id
+`
+
+func (q *Queries) ListAllNotes(ctx context.Context) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listAllNotes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.PinnedAt,
+			&i.ArchivedAt,
+			&i.Position,
+			&i.DueAt,
+			&i.UserID,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllTags = `-- name: ListAllTags :many
+SELECT id, nombre, color, workspace_id FROM tags
+ORDER BY id
+`
+
+func (q *Queries) ListAllTags(ctx context.Context) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listAllTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Color,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllWorkspaceMembers = `-- name: ListAllWorkspaceMembers :many
+SELECT workspace_id, user_id, role, created_at FROM workspace_members
+ORDER BY workspace_id, user_id
+`
+
+func (q *Queries) ListAllWorkspaceMembers(ctx context.Context) ([]WorkspaceMember, error) {
+	rows, err := q.db.QueryContext(ctx, listAllWorkspaceMembers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WorkspaceMember
+	for rows.Next() {
+		var i WorkspaceMember
+		if err := rows.Scan(
+			&i.WorkspaceID,
+			&i.UserID,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllWorkspaces = `-- name: ListAllWorkspaces :many
+SELECT id, nombre, created_at FROM workspaces
+ORDER BY id
+`
+
+func (q *Queries) ListAllWorkspaces(ctx context.Context) ([]Workspace, error) {
+	rows, err := q.db.QueryContext(ctx, listAllWorkspaces)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Workspace
+	for rows.Next() {
+		var i Workspace
+		if err := rows.Scan(&i.ID, &i.Nombre, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttachments = `-- name: ListAttachments :many
+SELECT a.id, a.note_id, a.filename, a.content_type, a.size, a.sha256, a.thumbnail_sha256, a.created_at FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NULL
+    AND n.workspace_id IS ?1
+    AND (n.workspace_id IS NOT NULL OR n.user_id = ?2)
+ORDER BY a.id
+`
+
+type ListAttachmentsParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+}
+
+func (q *Queries) ListAttachments(ctx context.Context, arg ListAttachmentsParams) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, listAttachments, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.Filename,
+			&i.ContentType,
+			&i.Size,
+			&i.Sha256,
+			&i.ThumbnailSha256,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAttachmentsForNote = `-- name: ListAttachmentsForNote :many
+SELECT id, note_id, filename, content_type, size, sha256, thumbnail_sha256, created_at FROM attachments
+WHERE note_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListAttachmentsForNote(ctx context.Context, noteID int64) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, listAttachmentsForNote, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.Filename,
+			&i.ContentType,
+			&i.Size,
+			&i.Sha256,
+			&i.ThumbnailSha256,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBacklinks = `-- name: ListBacklinks :many
+SELECT n.id, n.nombre, n.contenido, n.created_at, n.updated_at, n.deleted_at, n.version, n.pinned_at, n.archived_at, n.position, n.due_at, n.user_id, n.workspace_id FROM notes n
+JOIN note_links l ON l.source_id = n.id
+WHERE l.target_id = ?
+ORDER BY n.nombre
+`
+
+func (q *Queries) ListBacklinks(ctx context.Context, targetID int64) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listBacklinks, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.PinnedAt,
+			&i.ArchivedAt,
+			&i.Position,
+			&i.DueAt,
+			&i.UserID,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCommentsForNote = `-- name: ListCommentsForNote :many
+SELECT c.id, c.note_id, c.user_id, c.contenido, c.created_at, c.updated_at, u.username
+FROM note_comments c
+JOIN users u ON u.id = c.user_id
+WHERE c.note_id = ?
+ORDER BY c.created_at, c.id
+`
+
+type ListCommentsForNoteRow struct {
+	ID        int64        `json:"id"`
+	NoteID    int64        `json:"note_id"`
+	UserID    int64        `json:"user_id"`
+	Contenido string       `json:"contenido"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+	Username  string       `json:"username"`
+}
+
+func (q *Queries) ListCommentsForNote(ctx context.Context, noteID int64) ([]ListCommentsForNoteRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCommentsForNote, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCommentsForNoteRow
+	for rows.Next() {
+		var i ListCommentsForNoteRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.UserID,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueReminders = `-- name: ListDueReminders :many
+SELECT
+    r.id,
+    r.note_id,
+    r.fire_at,
+    r.attempts,
+    n.nombre,
+    n.due_at
+FROM reminders r
+JOIN notes n ON n.id = r.note_id
+WHERE r.sent_at IS NULL
+  AND (r.claimed_at IS NULL OR r.claimed_at <= ?1)
+  AND r.fire_at <= ?2
+  AND r.attempts < ?3
+  AND n.deleted_at IS NULL
+ORDER BY r.fire_at
+`
+
+type ListDueRemindersParams struct {
+	Stale       sql.NullTime `json:"stale"`
+	Now         time.Time    `json:"now"`
+	MaxAttempts int64        `json:"max_attempts"`
+}
+
+type ListDueRemindersRow struct {
+	ID       int64        `json:"id"`
+	NoteID   int64        `json:"note_id"`
+	FireAt   time.Time    `json:"fire_at"`
+	Attempts int64        `json:"attempts"`
+	Nombre   string       `json:"nombre"`
+	DueAt    sql.NullTime `json:"due_at"`
+}
+
+func (q *Queries) ListDueReminders(ctx context.Context, arg ListDueRemindersParams) ([]ListDueRemindersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDueReminders, arg.Stale, arg.Now, arg.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDueRemindersRow
+	for rows.Next() {
+		var i ListDueRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.FireAt,
+			&i.Attempts,
+			&i.Nombre,
+			&i.DueAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNoteLinks = `-- name: ListNoteLinks :many
+SELECT source_id, target_id FROM note_links
+ORDER BY source_id, target_id
+`
+
+func (q *Queries) ListNoteLinks(ctx context.Context) ([]NoteLink, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteLinks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NoteLink
+	for rows.Next() {
+		var i NoteLink
+		if err := rows.Scan(&i.SourceID, &i.TargetID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNoteRevisions = `-- name: ListNoteRevisions :many
+SELECT id, note_id, nombre, contenido, edited_at, created_at FROM note_revisions
+WHERE note_id = ?
+ORDER BY id DESC
+`
+
+func (q *Queries) ListNoteRevisions(ctx context.Context, noteID int64) ([]NoteRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteRevisions, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NoteRevision
+	for rows.Next() {
+		var i NoteRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.EditedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNoteShares = `-- name: ListNoteShares :many
+SELECT note_id, user_id, permission, created_at FROM note_shares
+ORDER BY note_id, user_id
+`
+
+func (q *Queries) ListNoteShares(ctx context.Context) ([]NoteShare, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteShares)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NoteShare
+	for rows.Next() {
+		var i NoteShare
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.UserID,
+			&i.Permission,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNoteTags = `-- name: ListNoteTags :many
+SELECT note_id, tag_id FROM note_tags
+ORDER BY note_id, tag_id
+`
+
+func (q *Queries) ListNoteTags(ctx context.Context) ([]NoteTag, error) {
+	rows, err := q.db.QueryContext(ctx, listNoteTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NoteTag
+	for rows.Next() {
+		var i NoteTag
+		if err := rows.Scan(&i.NoteID, &i.TagID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotes = `-- name: ListNotes :many
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
+WHERE deleted_at IS NULL
+ORDER BY id DESC
+`
+
+func (q *Queries) ListNotes(ctx context.Context) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listNotes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.PinnedAt,
+			&i.ArchivedAt,
+			&i.Position,
+			&i.DueAt,
+			&i.UserID,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotesMentioning = `-- name: ListNotesMentioning :many
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
+WHERE workspace_id IS ?1 AND (workspace_id IS NOT NULL OR user_id = ?2)
+    AND instr(lower(contenido), lower(?3)) > 0
+`
+
+type ListNotesMentioningParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+	Texto       string        `json:"texto"`
+}
+
+func (q *Queries) ListNotesMentioning(ctx context.Context, arg ListNotesMentioningParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listNotesMentioning, arg.WorkspaceID, arg.UserID, arg.Texto)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Note
+	for rows.Next() {
+		var i Note
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.PinnedAt,
+			&i.ArchivedAt,
+			&i.Position,
+			&i.DueAt,
+			&i.UserID,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotesSharedWith = `-- name: ListNotesSharedWith :many
+SELECT n.id, u.username AS owner_username, s.permission
+FROM note_shares s
+JOIN notes n ON n.id = s.note_id
+JOIN users u ON u.id = n.user_id
+WHERE s.user_id = ? AND n.deleted_at IS NULL
+ORDER BY n.updated_at DESC
+`
+
+type ListNotesSharedWithRow struct {
+	ID            int64  `json:"id"`
+	OwnerUsername string `json:"owner_username"`
+	Permission    string `json:"permission"`
+}
+
+func (q *Queries) ListNotesSharedWith(ctx context.Context, userID int64) ([]ListNotesSharedWithRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNotesSharedWith, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListNotesSharedWithRow
+	for rows.Next() {
+		var i ListNotesSharedWithRow
+		if err := rows.Scan(&i.ID, &i.OwnerUsername, &i.Permission); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotesWithTags = `-- name: ListNotesWithTags :many
+SELECT
+    n.id AS note_id,
+    n.nombre AS note_nombre,
+    n.contenido AS note_contenido,
+    n.created_at AS note_created_at,
+    n.updated_at AS note_updated_at,
+    n.version AS note_version,
+    n.pinned_at AS note_pinned_at,
+    n.archived_at AS note_archived_at,
+    n.position AS note_position,
+    n.due_at AS note_due_at,
+    n.user_id AS note_user_id,
+    t.id AS tag_id,
+    t.nombre AS tag_nombre,
+    t.color AS tag_color
+FROM
+    notes n
+        LEFT JOIN
+    note_tags nt ON n.id = nt.note_id
+        LEFT JOIN
+    tags t ON nt.tag_id = t.id
+WHERE
+    n.deleted_at IS NULL
+    AND n.workspace_id IS ?1
+    AND (n.workspace_id IS NOT NULL OR n.user_id = ?2)
+ORDER BY
+    n.id DESC
+`
+
+type ListNotesWithTagsParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+}
+
+type ListNotesWithTagsRow struct {
+	NoteID         int64          `json:"note_id"`
+	NoteNombre     string         `json:"note_nombre"`
+	NoteContenido  sql.NullString `json:"note_contenido"`
+	NoteCreatedAt  time.Time      `json:"note_created_at"`
+	NoteUpdatedAt  time.Time      `json:"note_updated_at"`
+	NoteVersion    int64          `json:"note_version"`
+	NotePinnedAt   sql.NullTime   `json:"note_pinned_at"`
+	NoteArchivedAt sql.NullTime   `json:"note_archived_at"`
+	NotePosition   string         `json:"note_position"`
+	NoteDueAt      sql.NullTime   `json:"note_due_at"`
+	NoteUserID     sql.NullInt64  `json:"note_user_id"`
+	TagID          sql.NullInt64  `json:"tag_id"`
+	TagNombre      sql.NullString `json:"tag_nombre"`
+	TagColor       sql.NullString `json:"tag_color"`
+}
+
+func (q *Queries) ListNotesWithTags(ctx context.Context, arg ListNotesWithTagsParams) ([]ListNotesWithTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNotesWithTags, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListNotesWithTagsRow
+	for rows.Next() {
+		var i ListNotesWithTagsRow
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.NoteNombre,
+			&i.NoteContenido,
+			&i.NoteCreatedAt,
+			&i.NoteUpdatedAt,
+			&i.NoteVersion,
+			&i.NotePinnedAt,
+			&i.NoteArchivedAt,
+			&i.NotePosition,
+			&i.NoteDueAt,
+			&i.NoteUserID,
+			&i.TagID,
+			&i.TagNombre,
+			&i.TagColor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublicLinksForNote = `-- name: ListPublicLinksForNote :many
+SELECT id, note_id, token, password_hash, expires_at, views, last_viewed_at, created_at, failed_attempts, locked_until FROM public_links
+WHERE note_id = ?
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListPublicLinksForNote(ctx context.Context, noteID int64) ([]PublicLink, error) {
+	rows, err := q.db.QueryContext(ctx, listPublicLinksForNote, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PublicLink
+	for rows.Next() {
+		var i PublicLink
+		if err := rows.Scan(
+			&i.ID,
+			&i.NoteID,
+			&i.Token,
+			&i.PasswordHash,
+			&i.ExpiresAt,
+			&i.Views,
+			&i.LastViewedAt,
+			&i.CreatedAt,
+			&i.FailedAttempts,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPurgeableAttachmentHashes = `-- name: ListPurgeableAttachmentHashes :many
+SELECT a.sha256 FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NOT NULL AND n.deleted_at < ?1
+UNION
+SELECT a.thumbnail_sha256 FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NOT NULL AND n.deleted_at < ?1 AND a.thumbnail_sha256 IS NOT NULL
+`
+
+func (q *Queries) ListPurgeableAttachmentHashes(ctx context.Context, cutoff sql.NullTime) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listPurgeableAttachmentHashes, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var sha256 string
+		if err := rows.Scan(&sha256); err != nil {
+			return nil, err
+		}
+		items = append(items, sha256)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSharesForNote = `-- name: ListSharesForNote :many
+SELECT s.user_id, u.username, s.permission, s.created_at
+FROM note_shares s
+JOIN users u ON u.id = s.user_id
+WHERE s.note_id = ?
+ORDER BY u.username
+`
+
+type ListSharesForNoteRow struct {
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (q *Queries) ListSharesForNote(ctx context.Context, noteID int64) ([]ListSharesForNoteRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSharesForNote, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSharesForNoteRow
+	for rows.Next() {
+		var i ListSharesForNoteRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Username,
+			&i.Permission,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTags = `-- name: ListTags :many
+SELECT id, nombre, color, workspace_id FROM tags
+WHERE workspace_id IS ?
+ORDER BY nombre
+`
+
+func (q *Queries) ListTags(ctx context.Context, workspaceID sql.NullInt64) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, listTags, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tag
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Color,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedNotes = `-- name: ListTrashedNotes :many
+SELECT id, nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id FROM notes
+WHERE deleted_at IS NOT NULL AND workspace_id IS ?1 AND (workspace_id IS NOT NULL OR user_id = ?2)
+ORDER BY deleted_at DESC
+`
+
+type ListTrashedNotesParams struct {
+	WorkspaceID sql.NullInt64 `json:"workspace_id"`
+	UserID      sql.NullInt64 `json:"user_id"`
+}
+
+func (q *Queries) ListTrashedNotes(ctx context.Context, arg ListTrashedNotesParams) ([]Note, error) {
+	rows, err := q.db.QueryContext(ctx, listTrashedNotes, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 	var items []Note
 	for rows.Next() {
 		var i Note
-		if err := rows.Scan(&i.ID, &i.Nombre, &i.Contenido); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Nombre,
+			&i.Contenido,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.PinnedAt,
+			&i.ArchivedAt,
+			&i.Position,
+			&i.DueAt,
+			&i.UserID,
+			&i.WorkspaceID,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -184,49 +2103,63 @@ func (q *Queries) ListNotes(ctx context.Context) ([]Note, error) {
 	return items, nil
 }
 
-const listNotesWithTags = `-- name: ListNotesWithTags :many
-SELECT
-    n.id AS note_id,
-    n.nombre AS note_nombre,
-    n.contenido AS note_contenido,
-    t.id AS tag_id,
-    t.nombre AS tag_nombre,
-    t.color AS tag_color
-FROM
-    notes n
-        LEFT JOIN
-    note_tags nt ON n.id = nt.note_id
-        LEFT JOIN
-    tags t ON nt.tag_id = t.id
-ORDER BY
-    n.id DESC
+const listUsers = `-- name: ListUsers :many
+SELECT id, username, password_hash, calendar_token FROM users
+ORDER BY id
 `
 
-type ListNotesWithTagsRow struct {
-	NoteID        int64          `json:"note_id"`
-	NoteNombre    string         `json:"note_nombre"`
-	NoteContenido sql.NullString `json:"note_contenido"`
-	TagID         sql.NullInt64  `json:"tag_id"`
-	TagNombre     sql.NullString `json:"tag_nombre"`
-	TagColor      sql.NullString `json:"tag_color"`
+func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.PasswordHash,
+			&i.CalendarToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListNotesWithTags(ctx context.Context) ([]ListNotesWithTagsRow, error) {
-	rows, err := q.db.QueryContext(ctx, listNotesWithTags)
+const listWorkspaceInvitations = `-- name: ListWorkspaceInvitations :many
+SELECT id, workspace_id, token, role, created_by, uses, expires_at, created_at FROM workspace_invitations
+WHERE workspace_id = ?
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListWorkspaceInvitations(ctx context.Context, workspaceID int64) ([]WorkspaceInvitation, error) {
+	rows, err := q.db.QueryContext(ctx, listWorkspaceInvitations, workspaceID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListNotesWithTagsRow
+	var items []WorkspaceInvitation
 	for rows.Next() {
-		var i ListNotesWithTagsRow
+		var i WorkspaceInvitation
 		if err := rows.Scan(
-			&i.NoteID,
-			&i.NoteNombre,
-			&i.NoteContenido,
-			&i.TagID,
-			&i.TagNombre,
-			&i.TagColor,
+			&i.ID,
+			&i.WorkspaceID,
+			&i.Token,
+			&i.Role,
+			&i.CreatedBy,
+			&i.Uses,
+			&i.ExpiresAt,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -241,21 +2174,73 @@ func (q *Queries) ListNotesWithTags(ctx context.Context) ([]ListNotesWithTagsRow
 	return items, nil
 }
 
-const listTags = `-- name: ListTags :many
-SELECT id, nombre, color FROM tags
-ORDER BY nombre
+const listWorkspaceMembers = `-- name: ListWorkspaceMembers :many
+SELECT m.user_id, u.username, m.role, m.created_at
+FROM workspace_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.workspace_id = ?
+ORDER BY u.username
 `
 
-func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
-	rows, err := q.db.QueryContext(ctx, listTags)
+type ListWorkspaceMembersRow struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) ListWorkspaceMembers(ctx context.Context, workspaceID int64) ([]ListWorkspaceMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listWorkspaceMembers, workspaceID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Tag
+	var items []ListWorkspaceMembersRow
 	for rows.Next() {
-		var i Tag
-		if err := rows.Scan(&i.ID, &i.Nombre, &i.Color); err != nil {
+		var i ListWorkspaceMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Username,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkspacesForUser = `-- name: ListWorkspacesForUser :many
+SELECT w.id, w.nombre, m.role
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE m.user_id = ?
+ORDER BY w.nombre COLLATE NOCASE
+`
+
+type ListWorkspacesForUserRow struct {
+	ID     int64  `json:"id"`
+	Nombre string `json:"nombre"`
+	Role   string `json:"role"`
+}
+
+func (q *Queries) ListWorkspacesForUser(ctx context.Context, userID int64) ([]ListWorkspacesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listWorkspacesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkspacesForUserRow
+	for rows.Next() {
+		var i ListWorkspacesForUserRow
+		if err := rows.Scan(&i.ID, &i.Nombre, &i.Role); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -269,6 +2254,268 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 	return items, nil
 }
 
+const markReminderSent = `-- name: MarkReminderSent :exec
+UPDATE reminders
+SET sent_at = ?, claimed_at = NULL
+WHERE id = ?
+`
+
+type MarkReminderSentParams struct {
+	SentAt sql.NullTime `json:"sent_at"`
+	ID     int64        `json:"id"`
+}
+
+func (q *Queries) MarkReminderSent(ctx context.Context, arg MarkReminderSentParams) error {
+	_, err := q.db.ExecContext(ctx, markReminderSent, arg.SentAt, arg.ID)
+	return err
+}
+
+const moveNote = `-- name: MoveNote :exec
+UPDATE notes
+SET position = ?
+WHERE id = ?
+`
+
+type MoveNoteParams struct {
+	Position string `json:"position"`
+	ID       int64  `json:"id"`
+}
+
+func (q *Queries) MoveNote(ctx context.Context, arg MoveNoteParams) error {
+	_, err := q.db.ExecContext(ctx, moveNote, arg.Position, arg.ID)
+	return err
+}
+
+const pinNote = `-- name: PinNote :exec
+UPDATE notes
+SET pinned_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) PinNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, pinNote, id)
+	return err
+}
+
+const purgeTrashedNotes = `-- name: PurgeTrashedNotes :execrows
+DELETE FROM notes
+WHERE deleted_at IS NOT NULL AND deleted_at < ?1
+`
+
+func (q *Queries) PurgeTrashedNotes(ctx context.Context, cutoff sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeTrashedNotes, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const releaseReminder = `-- name: ReleaseReminder :exec
+UPDATE reminders
+SET claimed_at = NULL, attempts = attempts + 1, last_error = ?
+WHERE id = ?
+`
+
+type ReleaseReminderParams struct {
+	LastError sql.NullString `json:"last_error"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) ReleaseReminder(ctx context.Context, arg ReleaseReminderParams) error {
+	_, err := q.db.ExecContext(ctx, releaseReminder, arg.LastError, arg.ID)
+	return err
+}
+
+const removeWorkspaceMember = `-- name: RemoveWorkspaceMember :execrows
+DELETE FROM workspace_members
+WHERE workspace_members.workspace_id = ?1 AND workspace_members.user_id = ?2
+  AND (workspace_members.role != 'admin' OR (
+    SELECT COUNT(*) FROM workspace_members a
+    WHERE a.workspace_id = ?1 AND a.role = 'admin'
+  ) > 1)
+`
+
+type RemoveWorkspaceMemberParams struct {
+	WorkspaceID int64 `json:"workspace_id"`
+	UserID      int64 `json:"user_id"`
+}
+
+// Igual que SetWorkspaceMemberRole, no se quita al ultimo administrador.
+func (q *Queries) RemoveWorkspaceMember(ctx context.Context, arg RemoveWorkspaceMemberParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeWorkspaceMember, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetPublicLinkAttempts = `-- name: ResetPublicLinkAttempts :exec
+UPDATE public_links
+SET failed_attempts = 0, locked_until = NULL
+WHERE id = ?
+`
+
+func (q *Queries) ResetPublicLinkAttempts(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, resetPublicLinkAttempts, id)
+	return err
+}
+
+const restoreNote = `-- name: RestoreNote :exec
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = ?
+`
+
+func (q *Queries) RestoreNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, restoreNote, id)
+	return err
+}
+
+const rewriteNoteContent = `-- name: RewriteNoteContent :exec
+UPDATE notes
+SET contenido = ?, version = version + 1
+WHERE id = ?
+`
+
+type RewriteNoteContentParams struct {
+	Contenido sql.NullString `json:"contenido"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) RewriteNoteContent(ctx context.Context, arg RewriteNoteContentParams) error {
+	_, err := q.db.ExecContext(ctx, rewriteNoteContent, arg.Contenido, arg.ID)
+	return err
+}
+
+const setBackupNoteContent = `-- name: SetBackupNoteContent :exec
+UPDATE notes
+SET contenido = ?
+WHERE id = ?
+`
+
+type SetBackupNoteContentParams struct {
+	Contenido sql.NullString `json:"contenido"`
+	ID        int64          `json:"id"`
+}
+
+func (q *Queries) SetBackupNoteContent(ctx context.Context, arg SetBackupNoteContentParams) error {
+	_, err := q.db.ExecContext(ctx, setBackupNoteContent, arg.Contenido, arg.ID)
+	return err
+}
+
+const setNoteDueAt = `-- name: SetNoteDueAt :exec
+UPDATE notes
+SET due_at = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = ?
+`
+
+type SetNoteDueAtParams struct {
+	DueAt sql.NullTime `json:"due_at"`
+	ID    int64        `json:"id"`
+}
+
+func (q *Queries) SetNoteDueAt(ctx context.Context, arg SetNoteDueAtParams) error {
+	_, err := q.db.ExecContext(ctx, setNoteDueAt, arg.DueAt, arg.ID)
+	return err
+}
+
+const setUserCalendarToken = `-- name: SetUserCalendarToken :exec
+UPDATE users
+SET calendar_token = ?
+WHERE id = ?
+`
+
+type SetUserCalendarTokenParams struct {
+	CalendarToken sql.NullString `json:"calendar_token"`
+	ID            int64          `json:"id"`
+}
+
+func (q *Queries) SetUserCalendarToken(ctx context.Context, arg SetUserCalendarTokenParams) error {
+	_, err := q.db.ExecContext(ctx, setUserCalendarToken, arg.CalendarToken, arg.ID)
+	return err
+}
+
+const setWorkspaceMemberRole = `-- name: SetWorkspaceMemberRole :execrows
+UPDATE workspace_members
+SET role = ?1
+WHERE workspace_members.workspace_id = ?2 AND workspace_members.user_id = ?3
+  AND (workspace_members.role = ?1 OR workspace_members.role != 'admin' OR (
+    SELECT COUNT(*) FROM workspace_members a
+    WHERE a.workspace_id = ?2 AND a.role = 'admin'
+  ) > 1)
+`
+
+type SetWorkspaceMemberRoleParams struct {
+	Role        string `json:"role"`
+	WorkspaceID int64  `json:"workspace_id"`
+	UserID      int64  `json:"user_id"`
+}
+
+// Un administrador solo deja de serlo si queda otro en el espacio. La cuenta
+// se hace en la misma sentencia para que dos cambios simultaneos no dejen el
+// espacio sin administradores.
+func (q *Queries) SetWorkspaceMemberRole(ctx context.Context, arg SetWorkspaceMemberRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setWorkspaceMemberRole, arg.Role, arg.WorkspaceID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const shareNote = `-- name: ShareNote :exec
+INSERT INTO note_shares (note_id, user_id, permission)
+VALUES (?, ?, ?)
+ON CONFLICT (note_id, user_id) DO UPDATE SET permission = excluded.permission
+`
+
+type ShareNoteParams struct {
+	NoteID     int64  `json:"note_id"`
+	UserID     int64  `json:"user_id"`
+	Permission string `json:"permission"`
+}
+
+func (q *Queries) ShareNote(ctx context.Context, arg ShareNoteParams) error {
+	_, err := q.db.ExecContext(ctx, shareNote, arg.NoteID, arg.UserID, arg.Permission)
+	return err
+}
+
+const trashNote = `-- name: TrashNote :exec
+UPDATE notes
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) TrashNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, trashNote, id)
+	return err
+}
+
+const unarchiveNote = `-- name: UnarchiveNote :exec
+UPDATE notes
+SET archived_at = NULL
+WHERE id = ?
+`
+
+func (q *Queries) UnarchiveNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, unarchiveNote, id)
+	return err
+}
+
+const unlinkTagFromNote = `-- name: UnlinkTagFromNote :exec
+DELETE FROM note_tags
+WHERE note_id = ? AND tag_id = ?
+`
+
+type UnlinkTagFromNoteParams struct {
+	NoteID int64 `json:"note_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+func (q *Queries) UnlinkTagFromNote(ctx context.Context, arg UnlinkTagFromNoteParams) error {
+	_, err := q.db.ExecContext(ctx, unlinkTagFromNote, arg.NoteID, arg.TagID)
+	return err
+}
+
 const unlinkTagsFromNote = `-- name: UnlinkTagsFromNote :exec
 DELETE FROM note_tags
 WHERE note_id = ?
@@ -279,19 +2526,83 @@ func (q *Queries) UnlinkTagsFromNote(ctx context.Context, noteID int64) error {
 	return err
 }
 
-const updateNote = `-- name: UpdateNote :exec
+const unpinNote = `-- name: UnpinNote :exec
 UPDATE notes
-SET nombre = ?, contenido = ?
+SET pinned_at = NULL
+WHERE id = ?
+`
+
+func (q *Queries) UnpinNote(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, unpinNote, id)
+	return err
+}
+
+const unshareNote = `-- name: UnshareNote :execrows
+DELETE FROM note_shares
+WHERE note_id = ? AND user_id = ?
+`
+
+type UnshareNoteParams struct {
+	NoteID int64 `json:"note_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) UnshareNote(ctx context.Context, arg UnshareNoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unshareNote, arg.NoteID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateComment = `-- name: UpdateComment :one
+UPDATE note_comments
+SET contenido = ?, updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
+RETURNING id, note_id, user_id, contenido, created_at, updated_at
+`
+
+type UpdateCommentParams struct {
+	Contenido string `json:"contenido"`
+	ID        int64  `json:"id"`
+}
+
+func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) (NoteComment, error) {
+	row := q.db.QueryRowContext(ctx, updateComment, arg.Contenido, arg.ID)
+	var i NoteComment
+	err := row.Scan(
+		&i.ID,
+		&i.NoteID,
+		&i.UserID,
+		&i.Contenido,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateNote = `-- name: UpdateNote :execrows
+UPDATE notes
+SET nombre = ?, contenido = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = ? AND version = ?
 `
 
 type UpdateNoteParams struct {
 	Nombre    string         `json:"nombre"`
 	Contenido sql.NullString `json:"contenido"`
 	ID        int64          `json:"id"`
+	Version   int64          `json:"version"`
 }
 
-func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) error {
-	_, err := q.db.ExecContext(ctx, updateNote, arg.Nombre, arg.Contenido, arg.ID)
-	return err
+func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateNote,
+		arg.Nombre,
+		arg.Contenido,
+		arg.ID,
+		arg.Version,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -0,0 +1,127 @@
+package diff
+
+import "strings"
+
+// Op es el tipo de cambio de una linea.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line es una linea del resultado del diff.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxTableCells limita el tamaño de la tabla de LCS (n*m enteros). Con textos
+// mas grandes que eso el tramo que cambio se muestra como reemplazado entero.
+const maxTableCells = 4 << 20
+
+// Lines calcula el diff linea a linea entre a (version anterior) y b (version nueva)
+// usando la subsecuencia comun mas larga.
+func Lines(a, b string) []Line {
+	return diff(splitLines(a), splitLines(b))
+}
+
+// HasChanges indica si el diff contiene alguna insercion o borrado.
+func HasChanges(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func diff(a, b []string) []Line {
+	// Se recortan el prefijo y el sufijo comunes para reducir la tabla de LCS.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var result []Line
+	for _, l := range a[:prefix] {
+		result = append(result, Line{Op: Equal, Text: l})
+	}
+	result = append(result, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: l})
+	}
+	return result
+}
+
+// lcs arma el diff a partir de la tabla de longitudes de la subsecuencia comun mas larga.
+func lcs(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n > 0 && m > maxTableCells/n {
+		return replaced(a, b)
+	}
+	// table[i][j] es la longitud de la LCS entre a[i:] y b[j:].
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	result := make([]Line, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			result = append(result, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: Insert, Text: b[j]})
+	}
+	return result
+}
+
+// replaced devuelve el diff que borra todas las lineas de a e inserta las de b.
+func replaced(a, b []string) []Line {
+	result := make([]Line, 0, len(a)+len(b))
+	for _, l := range a {
+		result = append(result, Line{Op: Delete, Text: l})
+	}
+	for _, l := range b {
+		result = append(result, Line{Op: Insert, Text: l})
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"ambos vacios", "", "", nil},
+		{"desde vacio", "", "uno\ndos", []Line{{Insert, "uno"}, {Insert, "dos"}}},
+		{"hasta vacio", "uno\ndos", "", []Line{{Delete, "uno"}, {Delete, "dos"}}},
+		{"iguales", "uno\ndos", "uno\ndos", []Line{{Equal, "uno"}, {Equal, "dos"}}},
+		{"salto final ignorado", "uno\n", "uno", []Line{{Equal, "uno"}}},
+		{"CRLF", "uno\r\ndos", "uno\ndos", []Line{{Equal, "uno"}, {Equal, "dos"}}},
+		{"agregado al final", "uno", "uno\ndos", []Line{{Equal, "uno"}, {Insert, "dos"}}},
+		{"agregado al principio", "dos", "uno\ndos", []Line{{Insert, "uno"}, {Equal, "dos"}}},
+		{"borrado al final", "uno\ndos", "uno", []Line{{Equal, "uno"}, {Delete, "dos"}}},
+		{"borrado al principio", "uno\ndos", "dos", []Line{{Delete, "uno"}, {Equal, "dos"}}},
+		{
+			"cambio en el medio",
+			"uno\ndos\ntres",
+			"uno\nDOS\ntres",
+			[]Line{{Equal, "uno"}, {Delete, "dos"}, {Insert, "DOS"}, {Equal, "tres"}},
+		},
+		{
+			"prefijo y sufijo comunes con lineas repetidas",
+			"a\nb\na\nb",
+			"a\nb\nx\na\nb",
+			[]Line{{Equal, "a"}, {Equal, "b"}, {Insert, "x"}, {Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			"lineas movidas",
+			"a\nb\nc",
+			"c\na\nb",
+			[]Line{{Insert, "c"}, {Equal, "a"}, {Equal, "b"}, {Delete, "c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) = %v, se esperaba %v", tt.a, tt.b, got, tt.want)
+			}
+			checkSides(t, tt.a, tt.b, got)
+		})
+	}
+}
+
+// TestLinesLarge verifica que con textos grandes se usa el reemplazo completo
+// del tramo que cambio en lugar de la tabla de LCS, conservando el prefijo y
+// el sufijo comunes.
+func TestLinesLarge(t *testing.T) {
+	var a, b []string
+	a = append(a, "inicio")
+	b = append(b, "inicio")
+	for i := 0; i < 3000; i++ {
+		a = append(a, fmt.Sprintf("antes %d", i))
+		b = append(b, fmt.Sprintf("despues %d", i))
+	}
+	a = append(a, "fin")
+	b = append(b, "fin")
+
+	got := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	checkSides(t, strings.Join(a, "\n"), strings.Join(b, "\n"), got)
+
+	if got[0] != (Line{Equal, "inicio"}) || got[len(got)-1] != (Line{Equal, "fin"}) {
+		t.Errorf("no se conservaron el prefijo y el sufijo: %v ... %v", got[0], got[len(got)-1])
+	}
+	if got[1].Op != Delete || got[3000].Op != Delete || got[3001].Op != Insert {
+		t.Errorf("se esperaban todos los borrados antes de las inserciones")
+	}
+}
+
+func TestHasChanges(t *testing.T) {
+	if HasChanges(Lines("uno\ndos", "uno\ndos")) {
+		t.Error("textos iguales no deberian tener cambios")
+	}
+	if !HasChanges(Lines("uno", "dos")) {
+		t.Error("textos distintos deberian tener cambios")
+	}
+}
+
+// checkSides verifica que las lineas iguales y borradas forman a, y las
+// iguales e insertadas forman b.
+func checkSides(t *testing.T, a, b string, lines []Line) {
+	t.Helper()
+	var before, after []string
+	for _, l := range lines {
+		if l.Op != Insert {
+			before = append(before, l.Text)
+		}
+		if l.Op != Delete {
+			after = append(after, l.Text)
+		}
+	}
+	if !reflect.DeepEqual(before, splitLines(a)) {
+		t.Errorf("el lado anterior no coincide: %q", before)
+	}
+	if !reflect.DeepEqual(after, splitLines(b)) {
+		t.Errorf("el lado nuevo no coincide: %q", after)
+	}
+}
@@ -0,0 +1,177 @@
+package flash
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Niveles de notificación. Coinciden con los iconos de SweetAlert2.
+const (
+	Success = "success"
+	Error   = "error"
+	Warning = "warning"
+	Info    = "info"
+)
+
+// cookieName es la cookie donde se guardan los mensajes que se mostrarán
+// en la siguiente carga completa de página.
+const cookieName = "flash"
+
+// TriggerEvent es el evento HTMX que se dispara con la cabecera HX-Trigger.
+const TriggerEvent = "notificacion"
+
+// Message es una notificación que se muestra al usuario como un toast.
+type Message struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+// contextKey para guardar los mensajes de la petición en el contexto.
+type contextKey string
+
+const storeContextKey = contextKey("flash")
+
+// store acumula los mensajes agregados por los handlers durante una petición.
+type store struct {
+	messages  []Message
+	delivered bool
+}
+
+// Middleware prepara el contexto para que los handlers puedan agregar mensajes
+// y se encarga de entregarlos al escribir la respuesta: en peticiones HTMX se
+// envían con la cabecera HX-Trigger y en el resto (o en redirecciones) se
+// guardan en una cookie que se consume al renderizar la siguiente página.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := &store{}
+		ctx := context.WithValue(r.Context(), storeContextKey, s)
+		fw := &responseWriter{ResponseWriter: w, r: r, store: s}
+		next.ServeHTTP(fw, r.WithContext(ctx))
+	})
+}
+
+// Push agrega un mensaje a la petición actual.
+func Push(r *http.Request, level, text string) {
+	s, ok := r.Context().Value(storeContextKey).(*store)
+	if !ok {
+		log.Printf("flash: mensaje descartado, falta el middleware: %s", text)
+		return
+	}
+	s.messages = append(s.messages, Message{Level: level, Text: text})
+}
+
+// Consume devuelve los mensajes pendientes (los guardados en la cookie y los
+// agregados en esta petición) para que se rendericen en la página, y borra la
+// cookie. Debe llamarse antes de escribir el cuerpo de la respuesta.
+func Consume(w http.ResponseWriter, r *http.Request) []Message {
+	var messages []Message
+
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		messages = append(messages, decode(cookie.Value)...)
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    "",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Path:     "/",
+		})
+	}
+
+	if s, ok := r.Context().Value(storeContextKey).(*store); ok {
+		messages = append(messages, s.messages...)
+		s.delivered = true
+	}
+
+	return messages
+}
+
+// responseWriter intercepta WriteHeader para entregar los mensajes pendientes
+// antes de que se envíen las cabeceras.
+type responseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	store       *store
+	wroteHeader bool
+}
+
+func (fw *responseWriter) WriteHeader(status int) {
+	if !fw.wroteHeader {
+		fw.wroteHeader = true
+		fw.deliver(status)
+	}
+	fw.ResponseWriter.WriteHeader(status)
+}
+
+func (fw *responseWriter) Write(b []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	return fw.ResponseWriter.Write(b)
+}
+
+// Unwrap permite que http.ResponseController acceda al ResponseWriter original.
+func (fw *responseWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
+
+func (fw *responseWriter) deliver(status int) {
+	if fw.store.delivered || len(fw.store.messages) == 0 {
+		return
+	}
+	fw.store.delivered = true
+
+	isRedirect := status >= 300 && status < 400
+	if fw.r.Header.Get("HX-Request") == "true" && !isRedirect {
+		setTrigger(fw.Header(), fw.store.messages)
+		return
+	}
+
+	// Se conservan los mensajes que todavía no se mostraron.
+	messages := fw.store.messages
+	if cookie, err := fw.r.Cookie(cookieName); err == nil {
+		messages = append(decode(cookie.Value), messages...)
+	}
+	http.SetCookie(fw, &http.Cookie{
+		Name:     cookieName,
+		Value:    encode(messages),
+		HttpOnly: true,
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setTrigger agrega el evento de notificación a la cabecera HX-Trigger.
+func setTrigger(h http.Header, messages []Message) {
+	payload, err := json.Marshal(map[string]any{
+		TriggerEvent: map[string]any{"mensajes": messages},
+	})
+	if err != nil {
+		log.Printf("flash: error codificando HX-Trigger: %v", err)
+		return
+	}
+	h.Set("HX-Trigger", string(payload))
+}
+
+func encode(messages []Message) string {
+	payload, err := json.Marshal(messages)
+	if err != nil {
+		log.Printf("flash: error codificando mensajes: %v", err)
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decode(value string) []Message {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	var messages []Message
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return nil
+	}
+	return messages
+}
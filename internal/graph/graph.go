@@ -0,0 +1,139 @@
+// Package graph arma el grafo de notas y tags para visualizar sus conexiones.
+package graph
+
+import "fmt"
+
+// Tipos de nodo y de arista.
+const (
+	NodeNote = "nota"
+	NodeTag  = "tag"
+
+	EdgeTag  = "tag"
+	EdgeLink = "enlace"
+)
+
+// Node es una nota o un tag del grafo.
+type Node struct {
+	ID    string `json:"id"`
+	Type  string `json:"tipo"`
+	Label string `json:"label"`
+	// Color es el color del tag; vacio en las notas.
+	Color string `json:"color,omitempty"`
+	// URL es la pagina de la nota; vacia en los tags.
+	URL string `json:"url,omitempty"`
+}
+
+// Edge une una nota con uno de sus tags, o una nota con otra que enlaza.
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"tipo"`
+}
+
+// Graph es el conjunto de nodos y aristas. Las aristas siempre unen nodos del grafo.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// NoteID devuelve el id de nodo de una nota.
+func NoteID(id int64) string {
+	return fmt.Sprintf("nota-%d", id)
+}
+
+// TagID devuelve el id de nodo de un tag.
+func TagID(id int64) string {
+	return fmt.Sprintf("tag-%d", id)
+}
+
+// Builder arma un grafo evitando nodos y aristas duplicados.
+type Builder struct {
+	graph Graph
+	nodes map[string]bool
+	edges map[Edge]bool
+}
+
+// NewBuilder crea un Builder vacio.
+func NewBuilder() *Builder {
+	return &Builder{
+		graph: Graph{Nodes: []Node{}, Edges: []Edge{}},
+		nodes: make(map[string]bool),
+		edges: make(map[Edge]bool),
+	}
+}
+
+// AddNode agrega un nodo si no existe.
+func (b *Builder) AddNode(n Node) {
+	if b.nodes[n.ID] {
+		return
+	}
+	b.nodes[n.ID] = true
+	b.graph.Nodes = append(b.graph.Nodes, n)
+}
+
+// AddEdge agrega una arista si no existe. Las aristas a nodos que no estan en
+// el grafo se descartan al llamar a Graph.
+func (b *Builder) AddEdge(e Edge) {
+	if b.edges[e] {
+		return
+	}
+	b.edges[e] = true
+	b.graph.Edges = append(b.graph.Edges, e)
+}
+
+// Graph devuelve el grafo armado.
+func (b *Builder) Graph() Graph {
+	return b.graph.keep(b.nodes)
+}
+
+// Neighbourhood devuelve el subgrafo de los nodos a lo sumo a depth aristas de
+// start, sin importar la direccion de las aristas. Si start no esta en el grafo
+// el resultado es vacio.
+func (g Graph) Neighbourhood(start string, depth int) Graph {
+	adjacent := make(map[string][]string)
+	for _, e := range g.Edges {
+		adjacent[e.Source] = append(adjacent[e.Source], e.Target)
+		adjacent[e.Target] = append(adjacent[e.Target], e.Source)
+	}
+
+	keep := make(map[string]bool)
+	for _, n := range g.Nodes {
+		if n.ID == start {
+			keep[start] = true
+		}
+	}
+	frontier := []string{}
+	if keep[start] {
+		frontier = append(frontier, start)
+	}
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, id := range frontier {
+			for _, neighbour := range adjacent[id] {
+				if !keep[neighbour] {
+					keep[neighbour] = true
+					next = append(next, neighbour)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return g.keep(keep)
+}
+
+// keep devuelve el grafo con solo los nodos de ids y las aristas entre ellos.
+func (g Graph) keep(ids map[string]bool) Graph {
+	out := Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, n := range g.Nodes {
+		if ids[n.ID] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if ids[e.Source] && ids[e.Target] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
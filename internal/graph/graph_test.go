@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sample arma el grafo nota-1 -- tag-1 -- nota-2 -> nota-3, con nota-4 suelta.
+func sample() Graph {
+	b := NewBuilder()
+	for i := int64(1); i <= 4; i++ {
+		b.AddNode(Node{ID: NoteID(i), Type: NodeNote})
+	}
+	b.AddNode(Node{ID: TagID(1), Type: NodeTag})
+	b.AddEdge(Edge{Source: NoteID(1), Target: TagID(1), Type: EdgeTag})
+	b.AddEdge(Edge{Source: NoteID(2), Target: TagID(1), Type: EdgeTag})
+	b.AddEdge(Edge{Source: NoteID(2), Target: NoteID(3), Type: EdgeLink})
+	return b.Graph()
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder()
+	b.AddNode(Node{ID: "nota-1", Label: "primera"})
+	b.AddNode(Node{ID: "nota-1", Label: "duplicada"})
+	b.AddNode(Node{ID: "nota-2"})
+	b.AddEdge(Edge{Source: "nota-1", Target: "nota-2", Type: EdgeLink})
+	b.AddEdge(Edge{Source: "nota-1", Target: "nota-2", Type: EdgeLink})
+	// La arista inversa es distinta: los enlaces tienen direccion
+	b.AddEdge(Edge{Source: "nota-2", Target: "nota-1", Type: EdgeLink})
+	// Aristas a notas que no estan en el grafo, como las de la papelera
+	b.AddEdge(Edge{Source: "nota-1", Target: "nota-9", Type: EdgeLink})
+
+	got := b.Graph()
+	want := Graph{
+		Nodes: []Node{{ID: "nota-1", Label: "primera"}, {ID: "nota-2"}},
+		Edges: []Edge{
+			{Source: "nota-1", Target: "nota-2", Type: EdgeLink},
+			{Source: "nota-2", Target: "nota-1", Type: EdgeLink},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Graph = %+v, se esperaba %+v", got, want)
+	}
+}
+
+func TestBuilderEmpty(t *testing.T) {
+	got := NewBuilder().Graph()
+	// Los slices vacios se codifican como [] en el JSON, no como null
+	if got.Nodes == nil || got.Edges == nil || len(got.Nodes) != 0 || len(got.Edges) != 0 {
+		t.Errorf("Graph vacio = %#v", got)
+	}
+}
+
+func TestNeighbourhood(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		depth int
+		nodes []string
+		edges int
+	}{
+		{"profundidad cero", NoteID(1), 0, []string{"nota-1"}, 0},
+		{"vecinos directos", NoteID(1), 1, []string{"nota-1", "tag-1"}, 1},
+		{"a traves del tag", NoteID(1), 2, []string{"nota-1", "nota-2", "tag-1"}, 2},
+		{"contra la direccion del enlace", NoteID(3), 1, []string{"nota-2", "nota-3"}, 1},
+		{"todo lo alcanzable", NoteID(1), 10, []string{"nota-1", "nota-2", "nota-3", "tag-1"}, 3},
+		{"nodo suelto", NoteID(4), 3, []string{"nota-4"}, 0},
+		{"nodo inexistente", NoteID(9), 3, nil, 0},
+	}
+	g := sample()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := g.Neighbourhood(tt.start, tt.depth)
+			var nodes []string
+			for _, n := range got.Nodes {
+				nodes = append(nodes, n.ID)
+			}
+			if !reflect.DeepEqual(nodes, tt.nodes) {
+				t.Errorf("nodos = %v, se esperaba %v", nodes, tt.nodes)
+			}
+			if len(got.Edges) != tt.edges {
+				t.Errorf("aristas = %v, se esperaban %d", got.Edges, tt.edges)
+			}
+		})
+	}
+}
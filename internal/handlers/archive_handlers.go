@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ArchiveHandler muestra las notas archivadas.
+func ArchiveHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	orden := parseNoteOrder(r)
+	filter := parseNoteFilter(r, true)
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener el archivo", http.StatusInternalServerError)
+		return
+	}
+
+	notes = filterNotes(notes, filter)
+	sortNotes(notes, orden)
+
+	data := map[string]any{
+		"Notes":    notes,
+		"Orden":    orden,
+		"Ordenes":  noteOrders,
+		"Busqueda": filter.Busqueda,
+	}
+
+	Render(tpl, w, r, "archivo.html", data)
+}
+
+// PinNoteHandler fija una nota para que aparezca primero en el listado.
+func PinNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, id, accessOwner); !ok {
+		return
+	}
+
+	if err := queries.PinNote(r.Context(), id); err != nil {
+		http.Error(w, "Error al fijar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota fijada")
+	redirectToList(w, r)
+}
+
+// UnpinNoteHandler quita una nota de las fijadas.
+func UnpinNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, id, accessOwner); !ok {
+		return
+	}
+
+	if err := queries.UnpinNote(r.Context(), id); err != nil {
+		http.Error(w, "Error al desfijar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota desfijada")
+	redirectToList(w, r)
+}
+
+// ArchiveNoteHandler archiva una nota y reemplaza su tarjeta por un aviso con
+// el enlace para deshacer.
+func ArchiveNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	if err := queries.ArchiveNote(r.Context(), id); err != nil {
+		http.Error(w, "Error al archivar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "nota_archivada.html", note)
+}
+
+// UnarchiveNoteHandler saca una nota del archivo y devuelve su tarjeta.
+func UnarchiveNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, id, accessOwner); !ok {
+		return
+	}
+
+	if err := queries.UnarchiveNote(r.Context(), id); err != nil {
+		http.Error(w, "Error al desarchivar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	note, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota desarchivada")
+	RenderPartial(tpl, w, "nota_card.html", note)
+}
+
+// redirectToList vuelve al listado desde el que se hizo la peticion (notas o
+// archivo, con sus filtros), para que se vea el nuevo orden de las notas.
+func redirectToList(w http.ResponseWriter, r *http.Request) {
+	target := "/notas"
+	// HTMX envia la URL de la pagina actual en la cabecera HX-Current-URL
+	if current, err := url.Parse(r.Header.Get("HX-Current-URL")); err == nil {
+		if current.Path == "/notas" || current.Path == "/archivo" {
+			target = current.Path
+			if current.RawQuery != "" {
+				target += "?" + current.RawQuery
+			}
+		}
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/storage"
+	"github.com/Calevin/go_htmx_crud/internal/thumbnail"
+	"github.com/Calevin/go_htmx_crud/internal/trash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// allowedContentTypes son los tipos MIME aceptados para los adjuntos. El tipo se
+// detecta a partir del contenido, no se confia en el que envia el navegador.
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+// inlineContentTypes se muestran en el navegador en lugar de descargarse.
+var inlineContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// errFileTooLarge se devuelve al leer un archivo que supera el tamaño maximo.
+var errFileTooLarge = errors.New("el archivo supera el tamaño máximo")
+
+// maxReader devuelve errFileTooLarge si se leen mas de remaining bytes.
+type maxReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// UploadAttachmentHandler recibe un archivo (multipart) y lo adjunta a la nota.
+// Devuelve el fragmento con la lista de adjuntos actualizada.
+func UploadAttachmentHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries, store storage.BlobStore, maxSize int64) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessEdit)
+	if !ok {
+		return
+	}
+	if note.DeletedAt.Valid {
+		http.Error(w, "Nota no encontrada", http.StatusNotFound)
+		return
+	}
+
+	// Se deja un margen para las cabeceras del multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Se esperaba un formulario multipart", http.StatusBadRequest)
+		return
+	}
+
+	// Se busca la parte con el archivo, sin cargar el resto del cuerpo en memoria
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, "Error al leer el formulario", http.StatusBadRequest)
+			return
+		}
+		if p.FormName() == "archivo" && p.FileName() != "" {
+			part, filename = p, p.FileName()
+			break
+		}
+	}
+	if part == nil {
+		http.Error(w, "No se envió ningún archivo", http.StatusBadRequest)
+		return
+	}
+
+	// Se detecta el tipo con los primeros 512 bytes antes de guardar nada
+	br := bufio.NewReaderSize(part, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		http.Error(w, "Error al leer el archivo", http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !allowedContentTypes[mediaType] {
+		http.Error(w, "Tipo de archivo no permitido: "+mediaType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Hasta que exista el adjunto nadie puede borrar el blob que se va a guardar
+	release := trash.HoldBlobs()
+	defer release()
+
+	key, size, err := store.Put(r.Context(), &maxReader{r: br, remaining: maxSize})
+	if errors.Is(err, errFileTooLarge) {
+		http.Error(w, "El archivo supera el tamaño máximo permitido", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Printf("Error guardando adjunto: %v", err)
+		http.Error(w, "Error al guardar el archivo", http.StatusInternalServerError)
+		return
+	}
+
+	// Las imagenes se guardan junto con una miniatura para mostrar en las tarjetas
+	var thumbnailKey sql.NullString
+	if thumbnail.Supported(mediaType) {
+		thumbnailKey = createThumbnail(r.Context(), store, key)
+	}
+
+	_, err = queries.CreateAttachment(r.Context(), db.CreateAttachmentParams{
+		NoteID:          id,
+		Filename:        cleanFilename(filename),
+		ContentType:     contentType,
+		Size:            size,
+		Sha256:          key,
+		ThumbnailSha256: thumbnailKey,
+	})
+	release()
+	if err != nil {
+		trash.RemoveOrphanBlobs(r.Context(), queries, store, []string{key, thumbnailKey.String})
+		http.Error(w, "Error al guardar el adjunto", http.StatusInternalServerError)
+		return
+	}
+
+	noteWithTags, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Archivo adjuntado")
+	RenderPartial(tpl, w, "adjuntos.html", noteWithTags)
+}
+
+// DownloadAttachmentHandler envia el contenido de un adjunto.
+func DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries, store storage.BlobStore) {
+	attachment, ok := getVisibleAttachment(w, r, queries, accessRead)
+	if !ok {
+		return
+	}
+
+	blob, err := store.Open(r.Context(), attachment.Sha256)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Archivo no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al abrir el archivo", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(attachment.ContentType)
+	disposition := "attachment"
+	if inlineContentTypes[mediaType] {
+		disposition = "inline"
+	}
+
+	// El contenido nunca cambia para una misma clave, se usa el hash como ETag
+	etag := `"` + attachment.Sha256 + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	// Evita que el navegador reinterprete el tipo o ejecute contenido del archivo
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox")
+
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("Error enviando adjunto %d: %v", attachment.ID, err)
+	}
+}
+
+// ThumbnailHandler envia la miniatura de un adjunto de imagen. Como un adjunto
+// nunca cambia, la respuesta se puede cachear indefinidamente.
+func ThumbnailHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries, store storage.BlobStore) {
+	attachment, ok := getVisibleAttachment(w, r, queries, accessRead)
+	if !ok {
+		return
+	}
+	if !attachment.ThumbnailSha256.Valid {
+		http.Error(w, "El adjunto no tiene miniatura", http.StatusNotFound)
+		return
+	}
+
+	etag := `"` + attachment.ThumbnailSha256.String + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	blob, err := store.Open(r.Context(), attachment.ThumbnailSha256.String)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Miniatura no encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al abrir la miniatura", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	// Las miniaturas de JPEG son JPEG y el resto PNG (ver thumbnail.Generate)
+	contentType := "image/png"
+	if strings.HasPrefix(attachment.ContentType, "image/jpeg") {
+		contentType = "image/jpeg"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("Error enviando miniatura del adjunto %d: %v", attachment.ID, err)
+	}
+}
+
+// createThumbnail genera la miniatura del blob key y la guarda en el almacenamiento.
+// Si la imagen no se puede procesar el adjunto se guarda igual, sin miniatura.
+func createThumbnail(ctx context.Context, store storage.BlobStore, key string) sql.NullString {
+	blob, err := store.Open(ctx, key)
+	if err != nil {
+		log.Printf("Error abriendo la imagen %s para la miniatura: %v", key, err)
+		return sql.NullString{}
+	}
+	defer blob.Close()
+
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		log.Printf("Error leyendo la imagen %s para la miniatura: %v", key, err)
+		return sql.NullString{}
+	}
+
+	thumb, _, err := thumbnail.Generate(data)
+	if err != nil {
+		log.Printf("Error generando la miniatura de %s: %v", key, err)
+		return sql.NullString{}
+	}
+
+	thumbKey, _, err := store.Put(ctx, bytes.NewReader(thumb))
+	if err != nil {
+		log.Printf("Error guardando la miniatura de %s: %v", key, err)
+		return sql.NullString{}
+	}
+	return sql.NullString{String: thumbKey, Valid: true}
+}
+
+// DeleteAttachmentHandler borra un adjunto y su blob si ya no se usa.
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries, store storage.BlobStore) {
+	attachment, ok := getVisibleAttachment(w, r, queries, accessEdit)
+	if !ok {
+		return
+	}
+
+	err := queries.DeleteAttachment(r.Context(), attachment.ID)
+	if err != nil {
+		http.Error(w, "Error al borrar el adjunto", http.StatusInternalServerError)
+		return
+	}
+	trash.RemoveOrphanBlobs(r.Context(), queries, store, []string{attachment.Sha256, attachment.ThumbnailSha256.String})
+
+	flash.Push(r, flash.Success, "Adjunto borrado")
+	w.WriteHeader(http.StatusOK)
+}
+
+// getVisibleAttachment obtiene el adjunto de la URL verificando que su nota no
+// este en la papelera y que el usuario tenga al menos el acceso need a ella.
+// Si no se puede acceder escribe el error y devuelve false.
+func getVisibleAttachment(w http.ResponseWriter, r *http.Request, queries *db.Queries, need noteAccess) (db.Attachment, bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return db.Attachment{}, false
+	}
+
+	attachment, err := queries.GetAttachment(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Adjunto no encontrado", http.StatusNotFound)
+		return db.Attachment{}, false
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, attachment.NoteID, need)
+	if !ok {
+		return db.Attachment{}, false
+	}
+	if note.DeletedAt.Valid {
+		http.Error(w, "Adjunto no encontrado", http.StatusNotFound)
+		return db.Attachment{}, false
+	}
+
+	return attachment, true
+}
+
+// cleanFilename deja solo el nombre base del archivo enviado por el navegador.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "archivo"
+	}
+	if len(name) > 200 {
+		name = strings.ToValidUTF8(name[:200], "")
+	}
+	return name
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/Calevin/go_htmx_crud/internal/auth"
 	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/middleware"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"time"
@@ -68,8 +70,20 @@ func LogoutHandler() http.HandlerFunc {
 			HttpOnly: true,
 			Path:     "/",
 		})
+		// Y el espacio de trabajo elegido, que es de este usuario
+		middleware.SetWorkspaceCookie(w, 0)
 		// Se redirige al login usando HTMX
 		w.Header().Set("HX-Redirect", "/login")
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// currentUser obtiene el usuario autenticado a partir de los claims que guarda
+// el middleware Authenticator en el contexto.
+func currentUser(r *http.Request, queries *db.Queries) (db.User, error) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		return db.User{}, errors.New("no hay un usuario autenticado")
+	}
+	return queries.GetUserByUsername(r.Context(), claims.Username)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"database/sql"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// BoardColumn es una columna del tablero: un tag con sus notas. La columna de
+// las notas sin tag tiene un Tag con ID 0.
+type BoardColumn struct {
+	Tag   db.Tag
+	Notes []*NoteWithTags
+}
+
+// BoardHandler muestra el tablero con una columna por tag. Las notas con varios
+// tags aparecen en todas sus columnas.
+func BoardHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := queries.ListTags(r.Context(), scope.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	// Las notas archivadas no se muestran en el tablero
+	notes = filterNotes(notes, noteFilter{})
+	// Se usa solo el orden personalizado, sin separar las fijadas, para que las
+	// vecinas de una tarjeta siempre esten en orden al moverla
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Position < notes[j].Position
+	})
+
+	columns := make([]*BoardColumn, 0, len(tags)+1)
+	byTag := make(map[int64]*BoardColumn, len(tags))
+	untagged := &BoardColumn{Tag: db.Tag{Nombre: "Sin tag"}}
+	for _, tag := range tags {
+		column := &BoardColumn{Tag: tag}
+		byTag[tag.ID] = column
+		columns = append(columns, column)
+	}
+
+	for _, note := range notes {
+		if len(note.Tags) == 0 {
+			untagged.Notes = append(untagged.Notes, note)
+			continue
+		}
+		for _, tag := range note.Tags {
+			if column, ok := byTag[tag.ID]; ok {
+				column.Notes = append(column.Notes, note)
+			}
+		}
+	}
+	// La columna sin tag solo se muestra si tiene notas
+	if len(untagged.Notes) > 0 {
+		columns = append(columns, untagged)
+	}
+
+	data := map[string]any{
+		"Columns": columns,
+	}
+
+	Render(tpl, w, r, "tablero.html", data)
+}
+
+// MoveCardHandler mueve una tarjeta del tablero de una columna a otra: se
+// quita el tag de la columna de origen y se agrega el de la columna de destino.
+// Tambien se actualiza su posicion si se pasan las notas vecinas.
+// Devuelve la tarjeta actualizada.
+func MoveCardHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, conn *sql.DB, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	from, err := strconv.ParseInt(r.FormValue("desde"), 10, 64)
+	if err != nil {
+		http.Error(w, "Columna de origen inválida", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.ParseInt(r.FormValue("hacia"), 10, 64)
+	if err != nil {
+		http.Error(w, "Columna de destino inválida", http.StatusBadRequest)
+		return
+	}
+
+	original, _, ok := authorizeNote(w, r, queries, id, accessEdit)
+	if !ok {
+		return
+	}
+
+	if from != to {
+		// El tag nuevo y el anterior se cambian juntos para que la nota no quede
+		// sin tag ni en dos columnas si falla uno de los pasos
+		tx, err := conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "Error al mover la tarjeta", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+		qtx := queries.WithTx(tx)
+
+		if to != 0 {
+			tag, err := qtx.GetTag(r.Context(), to)
+			if err != nil || !scopeOf(original).ContainsTag(tag) {
+				http.Error(w, "Tag no encontrado", http.StatusNotFound)
+				return
+			}
+			err = qtx.AddTagToNote(r.Context(), db.AddTagToNoteParams{
+				NoteID: id,
+				TagID:  tag.ID,
+			})
+			if err != nil {
+				http.Error(w, "Error al vincular el tag", http.StatusInternalServerError)
+				return
+			}
+		}
+		if from != 0 {
+			err = qtx.UnlinkTagFromNote(r.Context(), db.UnlinkTagFromNoteParams{
+				NoteID: id,
+				TagID:  from,
+			})
+			if err != nil {
+				http.Error(w, "Error al desvincular el tag", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Error al mover la tarjeta", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Posicion dentro de la columna, igual que en el orden personalizado
+	if r.FormValue("antes") != "" || r.FormValue("despues") != "" {
+		err = moveNote(r.Context(), queries, original, r.FormValue("antes"), r.FormValue("despues"), false)
+		if err != nil {
+			// El cambio de columna ya se guardo, solo se avisa que el orden no
+			flash.Push(r, flash.Warning, "No se pudo guardar la posición de la tarjeta")
+		}
+	}
+
+	note, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "tablero_tarjeta.html", note)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/ical"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// CalendarHandler muestra la URL del calendario del usuario. El token se
+// genera la primera vez que se entra a la pagina.
+func CalendarHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	token := user.CalendarToken.String
+	if !user.CalendarToken.Valid {
+		token, err = setCalendarToken(r, queries, user.ID)
+		if err != nil {
+			http.Error(w, "Error al generar el enlace del calendario", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	path := fmt.Sprintf("%s/calendario/%s.ics", r.Host, token)
+
+	data := map[string]any{
+		"URL":    requestScheme(r) + "://" + path,
+		"Webcal": "webcal://" + path,
+	}
+
+	Render(tpl, w, r, "calendario.html", data)
+}
+
+// RegenerateCalendarTokenHandler reemplaza el token del calendario; el enlace anterior deja de funcionar.
+func RegenerateCalendarTokenHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := setCalendarToken(r, queries, user.ID); err != nil {
+		http.Error(w, "Error al generar el enlace del calendario", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Se generó un nuevo enlace; el anterior ya no funciona")
+	http.Redirect(w, r, "/calendario", http.StatusSeeOther)
+}
+
+// CalendarFeedHandler publica las notas con fecha de vencimiento en formato
+// iCalendar. Es una ruta publica: el token secreto de la URL identifica al usuario.
+// Con ?tipo=tareas las notas se publican como tareas (VTODO) en lugar de eventos.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, err := queries.GetUserByCalendarToken(r.Context(), sql.NullString{String: token, Valid: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener el calendario", http.StatusInternalServerError)
+		return
+	}
+
+	// El calendario es del espacio personal: el token no sabe de espacios de trabajo
+	notes, err := listNotes(r.Context(), queries, personalScope(user.ID))
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	notes = filterNotes(notes, noteFilter{})
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].DueAt.Time.Before(notes[j].DueAt.Time)
+	})
+
+	entries := make([]ical.Entry, 0, len(notes))
+	for _, note := range notes {
+		if !note.DueAt.Valid {
+			continue
+		}
+		entries = append(entries, ical.Entry{
+			// El UID depende solo del id para que los calendarios actualicen la entrada
+			UID:         fmt.Sprintf("nota-%d@go_htmx_crud", note.ID),
+			Summary:     note.Nombre,
+			Description: note.Contenido,
+			Due:         note.DueAt.Time,
+			Modified:    note.UpdatedAt,
+			Sequence:    note.Version - 1,
+		})
+	}
+
+	kind := ical.Event
+	if r.URL.Query().Get("tipo") == "tareas" {
+		kind = ical.Todo
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="notas.ics"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if err := ical.Write(w, "Notas", kind, entries); err != nil {
+		log.Printf("Error enviando el calendario: %v", err)
+	}
+}
+
+// setCalendarToken genera y guarda un nuevo token secreto para el calendario del usuario.
+func setCalendarToken(r *http.Request, queries *db.Queries, userID int64) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	err := queries.SetUserCalendarToken(r.Context(), db.SetUserCalendarTokenParams{
+		ID:            userID,
+		CalendarToken: sql.NullString{String: token, Valid: true},
+	})
+	return token, err
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Comment es un comentario de una nota junto con el nombre de su autor.
+type Comment struct {
+	db.NoteComment
+	Autor string
+	// Propio indica si lo escribio el usuario actual, que puede editarlo y borrarlo.
+	Propio bool
+}
+
+// listComments devuelve los comentarios de la nota, del mas viejo al mas nuevo.
+func listComments(ctx context.Context, queries *db.Queries, noteID, userID int64) ([]Comment, error) {
+	rows, err := queries.ListCommentsForNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, 0, len(rows))
+	for _, row := range rows {
+		comments = append(comments, Comment{
+			NoteComment: db.NoteComment{
+				ID:        row.ID,
+				NoteID:    row.NoteID,
+				UserID:    row.UserID,
+				Contenido: row.Contenido,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+			},
+			Autor:  row.Username,
+			Propio: row.UserID == userID,
+		})
+	}
+	return comments, nil
+}
+
+// authorizeComment obtiene el comentario {id} de la URL y verifica que el
+// usuario todavia pueda ver su nota y, si own es true, que el comentario sea suyo.
+func authorizeComment(w http.ResponseWriter, r *http.Request, queries *db.Queries, own bool) (Comment, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return Comment{}, false
+	}
+
+	comment, err := queries.GetComment(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "El comentario no existe", http.StatusNotFound)
+		return Comment{}, false
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener el comentario", http.StatusInternalServerError)
+		return Comment{}, false
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, comment.NoteID, accessRead); !ok {
+		return Comment{}, false
+	}
+
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return Comment{}, false
+	}
+	if own && comment.UserID != user.ID {
+		http.Error(w, "Solo puedes modificar tus comentarios", http.StatusForbidden)
+		return Comment{}, false
+	}
+
+	return Comment{NoteComment: comment, Autor: user.Username, Propio: comment.UserID == user.ID}, true
+}
+
+// commentText obtiene el contenido del formulario de un comentario. Si esta
+// vacio escribe el error y devuelve false.
+func commentText(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return "", false
+	}
+	contenido := strings.TrimSpace(r.FormValue("contenido"))
+	if contenido == "" {
+		http.Error(w, "El comentario está vacío", http.StatusBadRequest)
+		return "", false
+	}
+	return contenido, true
+}
+
+// CreateCommentHandler agrega un comentario a la nota. Puede comentar
+// cualquiera que pueda ver la nota. Responde con el comentario para
+// agregarlo al final de la lista.
+func CreateCommentHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessRead)
+	if !ok {
+		return
+	}
+	if note.DeletedAt.Valid {
+		http.Error(w, "La nota está en la papelera", http.StatusConflict)
+		return
+	}
+
+	contenido, ok := commentText(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	comment, err := queries.CreateComment(r.Context(), db.CreateCommentParams{
+		NoteID:    id,
+		UserID:    user.ID,
+		Contenido: contenido,
+	})
+	if err != nil {
+		http.Error(w, "Error al guardar el comentario", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "comentario.html", Comment{NoteComment: comment, Autor: user.Username, Propio: true})
+}
+
+// CommentHandler devuelve un comentario; se usa al cancelar su edicion.
+func CommentHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	comment, ok := authorizeComment(w, r, queries, false)
+	if !ok {
+		return
+	}
+	if !comment.Propio {
+		// El autor de un comentario ajeno no es el usuario actual
+		author, err := queries.GetUser(r.Context(), comment.UserID)
+		if err != nil {
+			http.Error(w, "Error al obtener el autor del comentario", http.StatusInternalServerError)
+			return
+		}
+		comment.Autor = author.Username
+	}
+
+	RenderPartial(tpl, w, "comentario.html", comment)
+}
+
+// EditCommentFormHandler devuelve el formulario para editar un comentario propio.
+func EditCommentFormHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	comment, ok := authorizeComment(w, r, queries, true)
+	if !ok {
+		return
+	}
+
+	RenderPartial(tpl, w, "editar_comentario.html", comment)
+}
+
+// UpdateCommentHandler guarda los cambios de un comentario propio y responde
+// con el comentario actualizado.
+func UpdateCommentHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	comment, ok := authorizeComment(w, r, queries, true)
+	if !ok {
+		return
+	}
+
+	contenido, ok := commentText(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := queries.UpdateComment(r.Context(), db.UpdateCommentParams{
+		Contenido: contenido,
+		ID:        comment.ID,
+	})
+	if err != nil {
+		http.Error(w, "Error al guardar el comentario", http.StatusInternalServerError)
+		return
+	}
+
+	comment.NoteComment = updated
+	RenderPartial(tpl, w, "comentario.html", comment)
+}
+
+// DeleteCommentHandler borra un comentario propio.
+func DeleteCommentHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	comment, ok := authorizeComment(w, r, queries, true)
+	if !ok {
+		return
+	}
+
+	if err := queries.DeleteComment(r.Context(), comment.ID); err != nil {
+		http.Error(w, "Error al borrar el comentario", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Comentario borrado")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+	"github.com/Calevin/go_htmx_crud/internal/notefile"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxCSVSize es el tamaño maximo del CSV a importar.
+const maxCSVSize = 5 << 20
+
+// csvDateLayout es el formato de las fechas del CSV, que las planillas reconocen.
+const csvDateLayout = "2006-01-02 15:04"
+
+// csvTagSeparator separa los tags en la columna de tags del CSV.
+const csvTagSeparator = "; "
+
+// utf8BOM se escribe al principio del CSV para que Excel lo abra como UTF-8.
+const utf8BOM = "\ufeff"
+
+// csvFormulaPrefixes son los caracteres con los que una planilla interpreta
+// una celda como formula. Las notas pueden ser de otros usuarios (compartidas
+// o de un espacio de trabajo), asi que su texto no puede ejecutarse al abrir
+// el CSV: esas celdas se exportan con un apostrofo adelante.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// ExportCSVHandler descarga las notas en CSV, con los mismos filtros y el
+// mismo orden que el listado de /notas.
+func ExportCSVHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	orden := parseNoteOrder(r)
+	filter := parseNoteFilter(r, false)
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	notes = filterNotes(notes, filter)
+	sortNotes(notes, orden)
+
+	filename := fmt.Sprintf("notas-%s.csv", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	io.WriteString(w, utf8BOM)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "nombre", "contenido", "tags", "creada", "editada", "vence", "fijada", "archivada"})
+	for _, note := range notes {
+		tags := make([]string, 0, len(note.Tags))
+		for _, tag := range note.Tags {
+			tags = append(tags, tag.Nombre)
+		}
+		cw.Write([]string{
+			strconv.FormatInt(note.ID, 10),
+			csvText(note.Nombre),
+			csvText(note.Contenido),
+			csvText(strings.Join(tags, csvTagSeparator)),
+			note.CreatedAt.Local().Format(csvDateLayout),
+			note.UpdatedAt.Local().Format(csvDateLayout),
+			csvDate(note.DueAt),
+			csvDate(note.PinnedAt),
+			csvDate(note.ArchivedAt),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Error exportando CSV: %v", err)
+	}
+}
+
+// csvDate formatea una fecha opcional para el CSV.
+func csvDate(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Local().Format(csvDateLayout)
+}
+
+// csvText escapa una celda de texto para que no se tome como formula.
+func csvText(s string) string {
+	if s != "" && strings.IndexByte(csvFormulaPrefixes, s[0]) >= 0 {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnescape quita el apostrofo que agrega csvText, para que exportar e
+// importar devuelva el mismo texto.
+func csvUnescape(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.IndexByte(csvFormulaPrefixes, s[1]) >= 0 {
+		return s[1:]
+	}
+	return s
+}
+
+// csvFile es un CSV subido para importar.
+type csvFile struct {
+	// Columns son los nombres de las columnas: los del encabezado o "Columna N".
+	Columns []string
+	Rows    []csvRow
+	// Encabezado indica si la primera fila tiene los nombres de las columnas.
+	Encabezado bool
+}
+
+// csvRow es una fila del CSV. Line es la linea del archivo donde empieza.
+type csvRow struct {
+	Line   int
+	Fields []string
+	Err    string
+}
+
+// csvMapping indica que columna del CSV va a cada campo de la nota (-1 si ninguna).
+type csvMapping struct {
+	Nombre    int
+	Contenido int
+	Tags      int
+}
+
+// csvField es un campo de la nota en el paso de elegir las columnas.
+type csvField struct {
+	// Name es el nombre del campo del formulario.
+	Name  string
+	Label string
+	// Column es la columna sugerida (-1 si ninguna).
+	Column int
+}
+
+// Nombres de columna que se reconocen para cada campo al sugerir el mapeo.
+var (
+	csvNameColumns    = []string{"nombre", "name", "title", "titulo", "título"}
+	csvContentColumns = []string{"contenido", "content", "body", "texto", "descripcion", "descripción", "notas"}
+	csvTagColumns     = []string{"tags", "etiquetas", "labels", "categorias", "categorías"}
+)
+
+// ImportCSVHandler muestra el formulario para importar notas desde un CSV.
+func ImportCSVHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template) {
+	Render(tpl, w, r, "importar_csv.html", nil)
+}
+
+// CSVColumnsHandler lee el CSV subido y muestra sus columnas para elegir a que
+// campo de la nota corresponde cada una, con una sugerencia segun el encabezado.
+func CSVColumnsHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template) {
+	file, err := readCSVUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sample := file.Rows
+	if len(sample) > 3 {
+		sample = sample[:3]
+	}
+	mapping := guessCSVMapping(file)
+	RenderPartial(tpl, w, "importar_csv_columnas.html", map[string]any{
+		"File": file,
+		"Campos": []csvField{
+			{Name: "col_nombre", Label: "Nombre", Column: mapping.Nombre},
+			{Name: "col_contenido", Label: "Contenido", Column: mapping.Contenido},
+			{Name: "col_tags", Label: "Tags", Column: mapping.Tags},
+		},
+		"Sample": sample,
+	})
+}
+
+// CSVPreviewHandler muestra que notas se crearian con el CSV y el mapeo elegido.
+func CSVPreviewHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	files, err := readCSVImport(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	if err := planImport(r.Context(), queries, scope, files); err != nil {
+		http.Error(w, "Error al revisar las notas existentes", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "importar_resultado.html", map[string]any{
+		"Files":   files,
+		"Preview": true,
+	})
+}
+
+// ImportCSVNotesHandler importa las filas del CSV en una sola transaccion,
+// igual que la importacion de archivos Markdown.
+func ImportCSVNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer) {
+	files, err := readCSVImport(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+	if !scope.CanWrite() {
+		http.Error(w, "Tu rol en este espacio no permite crear notas", http.StatusForbidden)
+		return
+	}
+
+	imported, err := runImport(r.Context(), conn, queries, renderer, scope, files)
+	if err != nil {
+		log.Printf("Error importando CSV: %v", err)
+		flash.Push(r, flash.Error, "No se importó ninguna nota")
+	} else if imported == 0 {
+		flash.Push(r, flash.Warning, "No había notas nuevas para importar")
+	} else {
+		flash.Push(r, flash.Success, fmt.Sprintf("Notas importadas: %d", imported))
+	}
+
+	RenderPartial(tpl, w, "importar_resultado.html", map[string]any{
+		"Files":    files,
+		"Imported": imported,
+		"Failed":   err != nil,
+	})
+}
+
+// readCSVImport lee el CSV subido y convierte cada fila en una nota segun el
+// mapeo elegido. Las filas con errores quedan marcadas para el reporte.
+func readCSVImport(w http.ResponseWriter, r *http.Request) ([]*importFile, error) {
+	file, err := readCSVUpload(w, r)
+	if err != nil {
+		return nil, err
+	}
+	mapping := parseCSVMapping(r)
+	if mapping.Nombre < 0 {
+		return nil, errors.New("Elige la columna con el nombre de las notas")
+	}
+
+	var files []*importFile
+	for _, row := range file.Rows {
+		archivo := fmt.Sprintf("Fila %d", row.Line)
+		if row.Err != "" {
+			files = append(files, invalidImportFile(archivo, row.Err))
+			continue
+		}
+		if strings.TrimSpace(strings.Join(row.Fields, "")) == "" {
+			continue
+		}
+
+		note := notefile.Note{
+			Nombre:    strings.TrimSpace(csvValue(row, mapping.Nombre)),
+			Contenido: csvValue(row, mapping.Contenido),
+		}
+		for _, name := range splitCSVTags(csvValue(row, mapping.Tags)) {
+			note.Tags = append(note.Tags, notefile.Tag{Nombre: name})
+		}
+		if note.Nombre == "" {
+			files = append(files, invalidImportFile(archivo, "La fila no tiene nombre"))
+			continue
+		}
+		files = append(files, &importFile{Archivo: archivo, Nota: note, Estado: importNew})
+	}
+	if len(files) == 0 {
+		return nil, errors.New("El CSV no tiene filas para importar")
+	}
+	return files, nil
+}
+
+// readCSVUpload lee el CSV subido en el campo "archivo". Se quita el BOM y,
+// si el archivo no es UTF-8 valido, se interpreta como Latin-1 (el formato
+// que suele usar Excel en Windows). El separador se detecta en la primera linea.
+func readCSVUpload(w http.ResponseWriter, r *http.Request) (*csvFile, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCSVSize+1<<20)
+	if err := r.ParseMultipartForm(maxCSVSize); err != nil {
+		return nil, errors.New("No se pudo leer el archivo subido")
+	}
+	f, _, err := r.FormFile("archivo")
+	if err != nil {
+		return nil, errors.New("No se envió ningún archivo")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxCSVSize+1))
+	if err != nil {
+		return nil, errors.New("No se pudo leer el archivo subido")
+	}
+	if len(data) > maxCSVSize {
+		return nil, errors.New("El archivo supera el tamaño máximo de 5 MB")
+	}
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+	if !utf8.Valid(data) {
+		data = latin1ToUTF8(data)
+	}
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = sniffCSVSeparator(data)
+	cr.FieldsPerRecord = -1
+
+	file := &csvFile{Encabezado: r.FormValue("encabezado") != "no"}
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			file.Rows = append(file.Rows, csvRow{Line: parseErr.StartLine, Err: "CSV mal formado: " + parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, errors.New("No se pudo leer el CSV")
+		}
+		line, _ := cr.FieldPos(0)
+		file.Rows = append(file.Rows, csvRow{Line: line, Fields: fields})
+	}
+
+	columns := 0
+	for _, row := range file.Rows {
+		columns = max(columns, len(row.Fields))
+	}
+	if columns == 0 {
+		return nil, errors.New("El CSV está vacío")
+	}
+
+	var header []string
+	if file.Encabezado && len(file.Rows) > 0 && file.Rows[0].Err == "" {
+		header = file.Rows[0].Fields
+		file.Rows = file.Rows[1:]
+	}
+	for i := range columns {
+		name := ""
+		if i < len(header) {
+			name = strings.TrimSpace(header[i])
+		}
+		if name == "" {
+			name = fmt.Sprintf("Columna %d", i+1)
+		}
+		file.Columns = append(file.Columns, name)
+	}
+	return file, nil
+}
+
+// sniffCSVSeparator elige el separador mas frecuente de la primera linea entre
+// coma, punto y coma (el de Excel en español) y tabulador.
+func sniffCSVSeparator(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, count := ',', bytes.Count(line, []byte(","))
+	for _, sep := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(sep))); n > count {
+			best, count = sep, n
+		}
+	}
+	return best
+}
+
+// latin1ToUTF8 convierte texto en Latin-1 a UTF-8.
+func latin1ToUTF8(data []byte) []byte {
+	out := make([]rune, len(data))
+	for i, b := range data {
+		out[i] = rune(b)
+	}
+	return []byte(string(out))
+}
+
+// guessCSVMapping sugiere el mapeo de columnas segun los nombres del encabezado.
+func guessCSVMapping(file *csvFile) csvMapping {
+	find := func(names []string) int {
+		if !file.Encabezado {
+			return -1
+		}
+		for i, column := range file.Columns {
+			for _, name := range names {
+				if strings.EqualFold(column, name) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	m := csvMapping{
+		Nombre:    find(csvNameColumns),
+		Contenido: find(csvContentColumns),
+		Tags:      find(csvTagColumns),
+	}
+	// Sin encabezado se asume que la primera columna es el nombre
+	if m.Nombre < 0 && !file.Encabezado {
+		m.Nombre = 0
+	}
+	return m
+}
+
+// parseCSVMapping lee el mapeo de columnas elegido en el formulario.
+func parseCSVMapping(r *http.Request) csvMapping {
+	column := func(name string) int {
+		i, err := strconv.Atoi(r.FormValue(name))
+		if err != nil || i < 0 {
+			return -1
+		}
+		return i
+	}
+	return csvMapping{
+		Nombre:    column("col_nombre"),
+		Contenido: column("col_contenido"),
+		Tags:      column("col_tags"),
+	}
+}
+
+// csvValue devuelve el valor de la columna i de la fila, o vacio si no esta.
+func csvValue(row csvRow, i int) string {
+	if i < 0 || i >= len(row.Fields) {
+		return ""
+	}
+	return csvUnescape(row.Fields[i])
+}
+
+// splitCSVTags separa los tags de la columna de tags. Se usa el punto y coma,
+// como en la exportacion, o la coma si no hay ninguno.
+func splitCSVTags(s string) []string {
+	sep := ";"
+	if !strings.Contains(s, sep) {
+		sep = ","
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(s, sep) {
+		name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"texto", "texto"},
+		{"=SUMA(A1:A2)", "'=SUMA(A1:A2)"},
+		{"+54 11 1234", "'+54 11 1234"},
+		{"-3", "'-3"},
+		{"@usuario", "'@usuario"},
+		{"\tcon tab", "'\tcon tab"},
+		{"\rcon CR", "'\rcon CR"},
+		{"a=b", "a=b"},
+		{"'ya citado", "'ya citado"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.in); got != tt.want {
+			t.Errorf("csvText(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+		// Importar lo exportado devuelve el texto original
+		if got := csvUnescape(csvText(tt.in)); got != tt.in {
+			t.Errorf("csvUnescape(csvText(%q)) = %q", tt.in, got)
+		}
+	}
+}
+
+func TestCSVUnescape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"'", "'"},
+		{"'=1", "=1"},
+		{"'-", "-"},
+		{"'texto", "'texto"},
+		{"''=1", "''=1"},
+		{"=1", "=1"},
+	}
+	for _, tt := range tests {
+		if got := csvUnescape(tt.in); got != tt.want {
+			t.Errorf("csvUnescape(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCSVTags(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"vacio", "", nil},
+		{"punto y coma", "en curso; urgente", []string{"en curso", "urgente"}},
+		{"coma si no hay punto y coma", "a, b,c", []string{"a", "b", "c"}},
+		{"punto y coma antes que coma", "a, b; c", []string{"a, b", "c"}},
+		{"numeral", "#a; # b", []string{"a", "b"}},
+		{"duplicados sin distinguir mayusculas", "Casa; casa; CASA", []string{"Casa"}},
+		{"vacios", " ; ;a; #", []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitCSVTags(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCSVTags(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffCSVSeparator(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want rune
+	}{
+		{"coma", "nombre,contenido,tags\n1;2;3;4\n", ','},
+		{"punto y coma", "nombre;contenido;tags\n", ';'},
+		{"tabulacion", "nombre\tcontenido\n", '\t'},
+		{"una columna", "nombre\n", ','},
+		{"vacio", "", ','},
+		{"empate gana la coma", "a,b;c\n", ','},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffCSVSeparator([]byte(tt.data)); got != tt.want {
+				t.Errorf("sniffCSVSeparator(%q) = %q, se esperaba %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatin1ToUTF8(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte("hola"), "hola"},
+		{[]byte{'a', 0xf1, 'o'}, "año"},
+		{[]byte{0xc1, 0xe9, 0xfc, 0xbf}, "Áéü¿"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := string(latin1ToUTF8(tt.in)); got != tt.want {
+			t.Errorf("latin1ToUTF8(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/reminder"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// dueAtLayout es el formato de los campos datetime-local de los formularios.
+const dueAtLayout = "2006-01-02T15:04"
+
+// DueNotesHandler muestra las notas con fecha de vencimiento: las proximas a
+// vencer, de la mas cercana a la mas lejana, y las vencidas, de la mas reciente
+// a la mas antigua.
+func DueNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	// Las notas archivadas no se muestran
+	notes = filterNotes(notes, noteFilter{})
+
+	var upcoming, overdue []*NoteWithTags
+	for _, note := range notes {
+		switch {
+		case !note.DueAt.Valid:
+			continue
+		case note.Overdue():
+			overdue = append(overdue, note)
+		default:
+			upcoming = append(upcoming, note)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].DueAt.Time.Before(upcoming[j].DueAt.Time)
+	})
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DueAt.Time.After(overdue[j].DueAt.Time)
+	})
+
+	data := map[string]any{
+		"Proximas": upcoming,
+		"Vencidas": overdue,
+	}
+
+	Render(tpl, w, r, "vencimientos.html", data)
+}
+
+// parseDueAt lee la fecha de vencimiento del campo "vence" del formulario, en
+// hora local. Un campo vacio quita el vencimiento. El segundo valor indica si
+// el formulario tenia el campo.
+func parseDueAt(r *http.Request) (sql.NullTime, bool, error) {
+	values, ok := r.PostForm["vence"]
+	if !ok || len(values) == 0 || values[0] == "" {
+		return sql.NullTime{}, ok, nil
+	}
+
+	t, err := time.ParseInLocation(dueAtLayout, values[0], time.Local)
+	if err != nil {
+		return sql.NullTime{}, ok, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, true, nil
+}
+
+// setDueAt guarda la fecha de vencimiento de la nota y reprograma su recordatorio.
+// Cuenta como una modificacion de la nota: sube su version, que los calendarios
+// suscriptos usan (SEQUENCE) para saber que la fecha cambio.
+func setDueAt(ctx context.Context, queries *db.Queries, id int64, dueAt sql.NullTime, lead time.Duration) error {
+	err := queries.SetNoteDueAt(ctx, db.SetNoteDueAtParams{
+		ID:    id,
+		DueAt: dueAt,
+	})
+	if err != nil {
+		return err
+	}
+	return reminder.Schedule(ctx, queries, id, dueAt, lead)
+}
+
+// sameDueAt indica si dos fechas de vencimiento son iguales.
+func sameDueAt(a, b sql.NullTime) bool {
+	if a.Valid != b.Valid {
+		return false
+	}
+	return !a.Valid || a.Time.Equal(b.Time)
+}
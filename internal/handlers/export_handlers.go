@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"fmt"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/notefile"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ExportHandler descarga todas las notas, incluidas las archivadas, en un zip
+// con un archivo Markdown por nota. El zip se escribe directamente en la
+// respuesta a medida que se comprime cada nota, sin armarlo en memoria.
+func ExportHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	sortNotes(notes, "manual")
+
+	filename := fmt.Sprintf("notas-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Una vez enviado el primer byte ya no se puede responder con un error,
+	// asi que los errores solo se registran y el zip queda incompleto.
+	zw := zip.NewWriter(w)
+	for _, note := range notes {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     notefile.Filename(noteFile(note)),
+			Method:   zip.Deflate,
+			Modified: note.UpdatedAt,
+		})
+		if err == nil {
+			err = notefile.Encode(f, noteFile(note))
+		}
+		if err != nil {
+			log.Printf("Error exportando la nota %d: %v", note.ID, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Error cerrando el zip exportado: %v", err)
+	}
+}
+
+// noteFile convierte la nota al formato de archivo de notefile.
+func noteFile(note *NoteWithTags) notefile.Note {
+	n := notefile.Note{
+		ID:         note.ID,
+		Nombre:     note.Nombre,
+		Contenido:  note.Contenido,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+		DueAt:      nullTime(note.DueAt),
+		PinnedAt:   nullTime(note.PinnedAt),
+		ArchivedAt: nullTime(note.ArchivedAt),
+	}
+	for _, tag := range note.Tags {
+		n.Tags = append(n.Tags, notefile.Tag{Nombre: tag.Nombre, Color: tag.Color.String})
+	}
+	return n
+}
+
+// nullTime devuelve nil si t no es valido.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
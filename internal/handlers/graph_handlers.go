@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/graph"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// Profundidad por defecto y maxima al filtrar el grafo desde una nota.
+const (
+	defaultGraphDepth = 2
+	maxGraphDepth     = 6
+)
+
+// graphFilter son los filtros del grafo, tomados de la query.
+type graphFilter struct {
+	// TagID limita el grafo a las notas con ese tag (0 para todas).
+	TagID int64
+	// NoteID es la nota desde la que se recorre el grafo (0 para todo el grafo).
+	NoteID int64
+	// Depth es la cantidad maxima de aristas desde NoteID.
+	Depth int
+}
+
+// parseGraphFilter lee los filtros del grafo. Los valores invalidos se ignoran.
+func parseGraphFilter(r *http.Request) graphFilter {
+	q := r.URL.Query()
+	filter := graphFilter{Depth: defaultGraphDepth}
+	if id, err := strconv.ParseInt(q.Get("tag"), 10, 64); err == nil && id > 0 {
+		filter.TagID = id
+	}
+	if id, err := strconv.ParseInt(q.Get("nota"), 10, 64); err == nil && id > 0 {
+		filter.NoteID = id
+	}
+	if depth, err := strconv.Atoi(q.Get("profundidad")); err == nil && depth >= 1 {
+		filter.Depth = min(depth, maxGraphDepth)
+	}
+	return filter
+}
+
+// GraphHandler muestra la pagina del grafo, que obtiene los datos de /grafo.json.
+func GraphHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := queries.ListTags(r.Context(), scope.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	notes = filterNotes(notes, noteFilter{})
+	sortNotes(notes, "manual")
+
+	data := map[string]any{
+		"Tags":           tags,
+		"Notes":          notes,
+		"Filter":         parseGraphFilter(r),
+		"MaxProfundidad": maxGraphDepth,
+		"DataURL":        "/grafo.json?" + r.URL.RawQuery,
+	}
+
+	Render(tpl, w, r, "grafo.html", data)
+}
+
+// GraphDataHandler devuelve en JSON el grafo de notas y tags: las aristas unen
+// cada nota con sus tags y con las notas que enlaza. Se puede filtrar por tag
+// (?tag=id) y por cercania a una nota (?nota=id&profundidad=n).
+func GraphDataHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	filter := parseGraphFilter(r)
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := queries.ListTags(r.Context(), scope.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+	// Las notas archivadas no forman parte del grafo
+	notes = filterNotes(notes, noteFilter{})
+
+	b := graph.NewBuilder()
+	// Sin filtro de tag se muestran todos los tags, aunque no tengan notas
+	if filter.TagID == 0 {
+		for _, tag := range tags {
+			b.AddNode(tagNode(tag))
+		}
+	}
+
+	for _, note := range notes {
+		if filter.TagID != 0 && !hasTag(note, filter.TagID) {
+			continue
+		}
+		b.AddNode(graph.Node{
+			ID:    graph.NoteID(note.ID),
+			Type:  graph.NodeNote,
+			Label: note.Nombre,
+			URL:   "/nota/" + strconv.FormatInt(note.ID, 10),
+		})
+		for _, tag := range note.Tags {
+			b.AddNode(tagNode(tag))
+			b.AddEdge(graph.Edge{
+				Source: graph.NoteID(note.ID),
+				Target: graph.TagID(tag.ID),
+				Type:   graph.EdgeTag,
+			})
+		}
+		for _, source := range note.Backlinks {
+			b.AddEdge(graph.Edge{
+				Source: graph.NoteID(source.ID),
+				Target: graph.NoteID(note.ID),
+				Type:   graph.EdgeLink,
+			})
+		}
+	}
+
+	g := b.Graph()
+	if filter.NoteID != 0 {
+		g = g.Neighbourhood(graph.NoteID(filter.NoteID), filter.Depth)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(g); err != nil {
+		http.Error(w, "Error al generar el grafo", http.StatusInternalServerError)
+	}
+}
+
+// tagNode crea el nodo del grafo de un tag.
+func tagNode(tag db.Tag) graph.Node {
+	return graph.Node{
+		ID:    graph.TagID(tag.ID),
+		Type:  graph.NodeTag,
+		Label: tag.Nombre,
+		Color: tag.Color.String,
+	}
+}
+
+// hasTag indica si la nota tiene el tag.
+func hasTag(note *NoteWithTags, tagID int64) bool {
+	for _, tag := range note.Tags {
+		if tag.ID == tagID {
+			return true
+		}
+	}
+	return false
+}
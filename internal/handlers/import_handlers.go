@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+	"github.com/Calevin/go_htmx_crud/internal/notefile"
+	"html/template"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Limites de la importacion: tamaño total de lo subido y de cada nota, y
+// cuantas notas y cuantos bytes se leen en total una vez descomprimidos los zip.
+const (
+	maxImportSize     = 32 << 20
+	maxImportFileSize = 1 << 20
+	maxImportFiles    = 1000
+	maxImportExpanded = maxImportSize * 4
+)
+
+// errImportTooLarge se devuelve cuando lo subido supera maxImportFiles o
+// maxImportExpanded. Un zip chico puede descomprimirse a muchisimo mas de lo
+// que pesa, asi que no alcanza con limitar cada archivo.
+var errImportTooLarge = fmt.Errorf("La importación supera el máximo de %d notas o %d MB descomprimidos",
+	maxImportFiles, maxImportExpanded>>20)
+
+// importBudget es lo que queda por leer en una importacion.
+type importBudget struct {
+	files int
+	bytes int64
+}
+
+// open descuenta un archivo del presupuesto y devuelve un lector que descuenta
+// los bytes leidos de r, y falla con errImportTooLarge si se terminan.
+func (b *importBudget) open(r io.Reader) (io.Reader, error) {
+	if b.files == 0 {
+		return nil, errImportTooLarge
+	}
+	b.files--
+	return &budgetReader{r: r, budget: b}, nil
+}
+
+// budgetReader es un lector que descuenta lo leido de un importBudget.
+type budgetReader struct {
+	r      io.Reader
+	budget *importBudget
+}
+
+func (br *budgetReader) Read(p []byte) (int, error) {
+	if br.budget.bytes <= 0 {
+		return 0, errImportTooLarge
+	}
+	if int64(len(p)) > br.budget.bytes {
+		p = p[:br.budget.bytes]
+	}
+	n, err := br.r.Read(p)
+	br.budget.bytes -= int64(n)
+	return n, err
+}
+
+// Estados de cada archivo en la vista previa y en el reporte de la importacion.
+const (
+	importNew       = "nueva"
+	importDuplicate = "duplicada"
+	importInvalid   = "error"
+	importDone      = "importada"
+)
+
+// importFile es un archivo a importar y lo que pasa (o paso) con el.
+type importFile struct {
+	// Archivo es el nombre del archivo; los de un zip llevan el nombre del zip adelante.
+	Archivo string
+	Nota    notefile.Note
+	Estado  string
+	// Detalle explica el estado: el error o la nota con la que esta duplicada.
+	Detalle string
+	// TagsNuevos son los tags de la nota que no existen y se van a crear.
+	TagsNuevos []string
+	// NoteID es el id de la nota creada.
+	NoteID int64
+}
+
+// ImportHandler muestra el formulario para importar notas.
+func ImportHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template) {
+	Render(tpl, w, r, "importar.html", nil)
+}
+
+// ImportPreviewHandler muestra, sin guardar nada, que notas se crearian con
+// los archivos subidos y cuales se omitirian por duplicadas o por errores.
+func ImportPreviewHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	files, err := readImportFiles(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	if err := planImport(r.Context(), queries, scope, files); err != nil {
+		http.Error(w, "Error al revisar las notas existentes", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "importar_resultado.html", map[string]any{
+		"Files":   files,
+		"Preview": true,
+	})
+}
+
+// ImportNotesHandler importa los archivos subidos en una sola transaccion: si
+// falla alguna nota no se importa ninguna. Las duplicadas y las que tienen
+// errores se omiten. Responde con el resultado de cada archivo.
+func ImportNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer) {
+	files, err := readImportFiles(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+	if !scope.CanWrite() {
+		http.Error(w, "Tu rol en este espacio no permite crear notas", http.StatusForbidden)
+		return
+	}
+
+	imported, err := runImport(r.Context(), conn, queries, renderer, scope, files)
+	if err != nil {
+		log.Printf("Error importando notas: %v", err)
+		flash.Push(r, flash.Error, "No se importó ninguna nota")
+	} else if imported == 0 {
+		flash.Push(r, flash.Warning, "No había notas nuevas para importar")
+	} else {
+		flash.Push(r, flash.Success, fmt.Sprintf("Notas importadas: %d", imported))
+	}
+
+	RenderPartial(tpl, w, "importar_resultado.html", map[string]any{
+		"Files":    files,
+		"Imported": imported,
+		"Failed":   err != nil,
+	})
+}
+
+// readImportFiles lee los archivos Markdown subidos en el campo "archivos",
+// abriendo los zip. Los archivos que no se pueden leer quedan con estado de error.
+func readImportFiles(w http.ResponseWriter, r *http.Request) ([]*importFile, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		return nil, errors.New("No se pudieron leer los archivos subidos")
+	}
+	headers := r.MultipartForm.File["archivos"]
+	if len(headers) == 0 {
+		return nil, errors.New("No se envió ningún archivo")
+	}
+
+	budget := &importBudget{files: maxImportFiles, bytes: maxImportExpanded}
+	var files []*importFile
+	for _, header := range headers {
+		switch strings.ToLower(path.Ext(header.Filename)) {
+		case ".zip":
+			zipped, err := readImportZip(header, budget)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, zipped...)
+		case ".md", ".markdown", ".txt":
+			f, err := header.Open()
+			if err != nil {
+				files = append(files, invalidImportFile(header.Filename, "No se pudo abrir el archivo"))
+				continue
+			}
+			file, err := decodeImportFile(header.Filename, f, budget)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		default:
+			files = append(files, invalidImportFile(header.Filename, "Solo se pueden importar archivos .md o .zip"))
+		}
+	}
+	return files, nil
+}
+
+// readImportZip lee los archivos Markdown de un zip, ignorando los demas.
+// Solo devuelve error si se termina el presupuesto de la importacion.
+func readImportZip(header *multipart.FileHeader, budget *importBudget) ([]*importFile, error) {
+	f, err := header.Open()
+	if err != nil {
+		return []*importFile{invalidImportFile(header.Filename, "No se pudo abrir el archivo")}, nil
+	}
+	defer f.Close()
+
+	zr, err := zip.NewReader(f, header.Size)
+	if err != nil {
+		return []*importFile{invalidImportFile(header.Filename, "El archivo no es un zip válido")}, nil
+	}
+
+	var files []*importFile
+	for _, entry := range zr.File {
+		name := entry.Name
+		// Se saltean las carpetas y los archivos ocultos o de metadatos (ej. __MACOSX/)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(path.Base(name), ".") || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		if ext := strings.ToLower(path.Ext(name)); ext != ".md" && ext != ".markdown" {
+			continue
+		}
+
+		archivo := header.Filename + "/" + name
+		rc, err := entry.Open()
+		if err != nil {
+			files = append(files, invalidImportFile(archivo, "No se pudo leer el archivo"))
+			continue
+		}
+		file, err := decodeImportFile(archivo, rc, budget)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return []*importFile{invalidImportFile(header.Filename, "El zip no tiene archivos .md")}, nil
+	}
+	return files, nil
+}
+
+// decodeImportFile lee una nota, con un tamaño maximo para no cargar
+// archivos enormes (o un zip que se descomprime a mucho mas de lo que pesa).
+// Solo devuelve error si se termina el presupuesto de la importacion; los
+// demas problemas quedan en el estado del archivo.
+func decodeImportFile(archivo string, r io.Reader, budget *importBudget) (*importFile, error) {
+	r, err := budget.open(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxImportFileSize+1))
+	if errors.Is(err, errImportTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return invalidImportFile(archivo, "No se pudo leer el archivo"), nil
+	}
+	if len(data) > maxImportFileSize {
+		return invalidImportFile(archivo, "El archivo supera el tamaño máximo de 1 MB"), nil
+	}
+
+	note, err := notefile.Decode(archivo, data)
+	if err != nil {
+		return invalidImportFile(archivo, err.Error()), nil
+	}
+	return &importFile{Archivo: archivo, Nota: note, Estado: importNew}, nil
+}
+
+// invalidImportFile crea un archivo con estado de error.
+func invalidImportFile(archivo, detalle string) *importFile {
+	return &importFile{Archivo: archivo, Estado: importInvalid, Detalle: detalle}
+}
+
+// planImport marca como duplicadas las notas con el mismo nombre (sin
+// distinguir mayusculas) que una nota del espacio o que un archivo anterior, y
+// calcula que tags hay que crear.
+func planImport(ctx context.Context, queries *db.Queries, scope noteScope, files []*importFile) error {
+	tags, err := existingTags(ctx, queries, scope)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string)
+	for _, file := range files {
+		if file.Estado != importNew {
+			continue
+		}
+
+		key := strings.ToLower(file.Nota.Nombre)
+		if other, ok := names[key]; ok {
+			file.Estado = importDuplicate
+			file.Detalle = "Tiene el mismo nombre que " + other
+			continue
+		}
+		names[key] = file.Archivo
+
+		existing, err := queries.GetNoteByName(ctx, db.GetNoteByNameParams{
+			WorkspaceID: scope.WorkspaceID,
+			UserID:      ownedBy(scope.UserID),
+			Nombre:      file.Nota.Nombre,
+		})
+		if err == nil {
+			file.Estado = importDuplicate
+			file.Detalle = fmt.Sprintf("Ya existe la nota #%d \"%s\"", existing.ID, existing.Nombre)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		for _, tag := range file.Nota.Tags {
+			if _, ok := tags[strings.ToLower(tag.Nombre)]; !ok {
+				file.TagsNuevos = append(file.TagsNuevos, tag.Nombre)
+			}
+		}
+	}
+	return nil
+}
+
+// runImport crea las notas nuevas de files en el espacio en una transaccion
+// y devuelve cuantas se importaron. Si falla alguna se deshace todo y el archivo que
+// fallo queda con estado de error.
+func runImport(ctx context.Context, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer, scope noteScope, files []*importFile) (int, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// Se revisan los duplicados dentro de la transaccion, asi no cambian antes de importar
+	if err := planImport(ctx, qtx, scope, files); err != nil {
+		return 0, err
+	}
+	tags, err := existingTags(ctx, qtx, scope)
+	if err != nil {
+		return 0, err
+	}
+
+	// Cada nota se agrega arriba de todo, asi que se recorren al reves para
+	// que queden en el orden en que se subieron
+	imported := 0
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		if file.Estado != importNew {
+			continue
+		}
+		id, err := importNote(ctx, qtx, renderer, scope, tags, file.Nota)
+		if err != nil {
+			file.Estado = importInvalid
+			file.Detalle = "Error al guardar la nota"
+			return 0, fmt.Errorf("%s: %w", file.Archivo, err)
+		}
+		file.NoteID = id
+		imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if file.NoteID != 0 {
+			file.Estado = importDone
+		}
+	}
+	return imported, nil
+}
+
+// importNote crea la nota con sus tags, creando los tags que no existen, y
+// guarda sus enlaces a otras notas.
+func importNote(ctx context.Context, queries *db.Queries, renderer *markdown.Renderer, scope noteScope, tags map[string]db.Tag, n notefile.Note) (int64, error) {
+	note, err := queries.CreateNote(ctx, db.CreateNoteParams{
+		Nombre:      n.Nombre,
+		Contenido:   sql.NullString{String: n.Contenido, Valid: true},
+		UserID:      ownedBy(scope.UserID),
+		WorkspaceID: scope.WorkspaceID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := moveToTop(ctx, queries, note.ID); err != nil {
+		return 0, err
+	}
+
+	for _, t := range n.Tags {
+		key := strings.ToLower(t.Nombre)
+		tag, ok := tags[key]
+		if !ok {
+			tag, err = queries.CreateTag(ctx, db.CreateTagParams{
+				Nombre:      t.Nombre,
+				Color:       sql.NullString{String: t.Color, Valid: t.Color != ""},
+				WorkspaceID: scope.WorkspaceID,
+			})
+			if err != nil {
+				return 0, err
+			}
+			tags[key] = tag
+		}
+		err := queries.LinkTagToNote(ctx, db.LinkTagToNoteParams{NoteID: note.ID, TagID: tag.ID})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := noteSaved(ctx, queries, renderer, note.ID, "", note.Nombre, n.Contenido); err != nil {
+		return 0, err
+	}
+	return note.ID, nil
+}
+
+// existingTags devuelve los tags del espacio indexados por nombre en minusculas.
+func existingTags(ctx context.Context, queries *db.Queries, scope noteScope) (map[string]db.Tag, error) {
+	list, err := queries.ListTags(ctx, scope.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]db.Tag, len(list))
+	for _, tag := range list {
+		tags[strings.ToLower(tag.Nombre)] = tag
+	}
+	return tags, nil
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// LinkedNote es una nota que enlaza a otra, para la seccion "Enlazada desde".
+type LinkedNote struct {
+	ID     int64
+	Nombre string
+}
+
+// NoteHandler muestra una nota junto con las notas que la enlazan y sus comentarios.
+func NoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, access, ok := authorizeNote(w, r, queries, id, accessRead)
+	if !ok {
+		return
+	}
+	if note.DeletedAt.Valid {
+		http.Error(w, "La nota está en la papelera", http.StatusNotFound)
+		return
+	}
+
+	noteWithTags, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+	if err := setSharedAccess(r.Context(), queries, noteWithTags, access, note); err != nil {
+		http.Error(w, "Error al obtener el dueño de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+	comments, err := listComments(r.Context(), queries, id, user.ID)
+	if err != nil {
+		http.Error(w, "Error al obtener los comentarios", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Note":     noteWithTags,
+		"Comments": comments,
+	}
+
+	Render(tpl, w, r, "nota.html", data)
+}
+
+// GoToNoteHandler redirige a la nota del espacio actual con el nombre pasado
+// en la query. Los enlaces [[Nombre]] se resuelven aca, al hacer clic.
+func GoToNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	nombre := strings.TrimSpace(r.URL.Query().Get("nombre"))
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	note, err := queries.GetNoteByName(r.Context(), db.GetNoteByNameParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+		Nombre:      nombre,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		flash.Push(r, flash.Warning, "No existe una nota llamada \""+nombre+"\"")
+		http.Redirect(w, r, "/notas", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al buscar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/nota/"+strconv.FormatInt(note.ID, 10), http.StatusSeeOther)
+}
+
+// noteSaved actualiza los enlaces despues de crear o editar una nota: guarda
+// los enlaces que salen de ella y, si cambio el nombre, actualiza los enlaces
+// que apuntan a ella. oldName es vacio para las notas nuevas.
+// Los enlaces se resuelven entre las notas del espacio de la nota (el personal
+// de su dueño o un espacio de trabajo), aunque la edite otro usuario.
+func noteSaved(ctx context.Context, queries *db.Queries, renderer *markdown.Renderer, id int64, oldName, newName, contenido string) error {
+	note, err := queries.GetNote(ctx, id)
+	if err != nil {
+		return err
+	}
+	scope := scopeOf(note)
+
+	if err := syncLinks(ctx, queries, renderer, scope, id, contenido); err != nil {
+		return err
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	if oldName != "" {
+		if err := renameLinks(ctx, queries, renderer, id, oldName, newName); err != nil {
+			return err
+		}
+	}
+	// Los enlaces al nuevo nombre que antes no llevaban a ninguna nota ahora se resuelven
+	return resolvePendingLinks(ctx, queries, renderer, scope, newName)
+}
+
+// syncLinks reemplaza los enlaces guardados de la nota id por los que hay en contenido.
+// Los enlaces a notas que no existen o que no son del espacio no se guardan.
+func syncLinks(ctx context.Context, queries *db.Queries, renderer *markdown.Renderer, scope noteScope, id int64, contenido string) error {
+	if err := queries.DeleteNoteLinksFrom(ctx, id); err != nil {
+		return err
+	}
+
+	for _, link := range renderer.WikiLinks(contenido) {
+		targetID := link.ID
+		if link.Name != "" {
+			target, err := queries.GetNoteByName(ctx, db.GetNoteByNameParams{
+				WorkspaceID: scope.WorkspaceID,
+				UserID:      ownedBy(scope.UserID),
+				Nombre:      link.Name,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			targetID = target.ID
+		} else if target, err := queries.GetNote(ctx, targetID); errors.Is(err, sql.ErrNoRows) || (err == nil && !scope.Contains(target)) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if targetID == id {
+			continue
+		}
+		err := queries.CreateNoteLink(ctx, db.CreateNoteLinkParams{
+			SourceID: id,
+			TargetID: targetID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameLinks reescribe los enlaces [[oldName]] de las notas que enlazan a la
+// nota id para que sigan funcionando con su nuevo nombre. Se incrementa la
+// version de las notas modificadas, asi los formularios abiertos detectan el cambio.
+func renameLinks(ctx context.Context, queries *db.Queries, renderer *markdown.Renderer, id int64, oldName, newName string) error {
+	sources, err := queries.ListBacklinks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		contenido := renderer.RenameWikiLinks(source.Contenido.String, oldName, newName)
+		if contenido == source.Contenido.String {
+			continue
+		}
+		err := queries.RewriteNoteContent(ctx, db.RewriteNoteContentParams{
+			ID:        source.ID,
+			Contenido: sql.NullString{String: contenido, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePendingLinks vuelve a calcular los enlaces de las notas del espacio que mencionan [[nombre.
+func resolvePendingLinks(ctx context.Context, queries *db.Queries, renderer *markdown.Renderer, scope noteScope, nombre string) error {
+	notes, err := queries.ListNotesMentioning(ctx, db.ListNotesMentioningParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+		Texto:       "[[" + nombre,
+	})
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		if err := syncLinks(ctx, queries, renderer, scope, note.ID, note.Contenido.String); err != nil {
+			return err
+		}
+	}
+	return nil
+}
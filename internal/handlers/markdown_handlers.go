@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// PreviewHandler renderiza el contenido enviado por el formulario para la vista previa.
+func PreviewHandler(w http.ResponseWriter, r *http.Request, renderer *markdown.Renderer) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, string(renderer.Render(r.FormValue("contenido"))))
+}
+
+// ToggleTaskHandler marca o desmarca una tarea de la lista de una nota y devuelve
+// la tarjeta actualizada. Solo se reescribe la linea de esa tarea.
+func ToggleTaskHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries, renderer *markdown.Renderer) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(r.FormValue("tarea"))
+	if err != nil {
+		http.Error(w, "Tarea inválida", http.StatusBadRequest)
+		return
+	}
+
+	version, err := strconv.ParseInt(r.FormValue("version"), 10, 64)
+	if err != nil {
+		http.Error(w, "Versión de la nota inválida", http.StatusBadRequest)
+		return
+	}
+
+	note, access, ok := authorizeNote(w, r, queries, id, accessEdit)
+	if !ok {
+		return
+	}
+
+	updated := int64(0)
+	// Si la tarjeta esta desactualizada no se modifica nada, se devuelve la version actual
+	if note.Version == version {
+		contenido, err := renderer.ToggleTask(note.Contenido.String, index)
+		if errors.Is(err, markdown.ErrTaskNotFound) {
+			http.Error(w, "La tarea no existe", http.StatusBadRequest)
+			return
+		}
+
+		updated, err = queries.UpdateNote(r.Context(), db.UpdateNoteParams{
+			ID:     id,
+			Nombre: note.Nombre,
+			Contenido: sql.NullString{
+				String: contenido,
+				Valid:  true,
+			},
+			Version: version,
+		})
+		if err != nil {
+			http.Error(w, "Error al actualizar la nota", http.StatusInternalServerError)
+			return
+		}
+	}
+	if updated == 0 {
+		flash.Push(r, flash.Warning, "La nota había cambiado, se muestra la versión actual")
+	}
+
+	noteWithTags, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+	if err := setSharedAccess(r.Context(), queries, noteWithTags, access, note); err != nil {
+		http.Error(w, "Error al obtener el dueño de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "nota_card.html", noteWithTags)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/rank"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// MoveNoteHandler cambia la posicion de una nota en el orden personalizado.
+// Recibe los ids de las notas que quedan antes y despues de ella; cualquiera
+// de los dos puede faltar si la nota se movio al principio o al final.
+// Solo se actualiza la posicion de la nota movida.
+func MoveNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	err = moveNote(r.Context(), queries, note, r.FormValue("antes"), r.FormValue("despues"), true)
+	if errors.Is(err, errInvalidNeighbour) {
+		http.Error(w, "Nota vecina inválida", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, rank.ErrInvalidRange) || errors.Is(err, rank.ErrInvalidKey) {
+		// Las posiciones cambiaron desde que se cargo la lista
+		flash.Push(r, flash.Warning, "El orden de las notas cambió, recarga la página")
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al mover la nota", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errInvalidNeighbour se devuelve cuando el id de una nota vecina no es valido.
+var errInvalidNeighbour = errors.New("nota vecina inválida")
+
+// moveNote ubica la nota entre las notas antes y despues (ids en texto,
+// vacios para el principio o el final de la lista). pinnedFirst indica que la
+// lista muestra primero las fijadas, como el listado de notas: la posicion de
+// una fijada no se compara con la de una que no lo esta, asi que una vecina
+// del otro grupo se toma como el borde del grupo de la nota.
+func moveNote(ctx context.Context, queries *db.Queries, note db.Note, antes, despues string, pinnedFirst bool) error {
+	before, err := neighbourPosition(ctx, queries, note, antes, pinnedFirst)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidNeighbour, err)
+	}
+	after, err := neighbourPosition(ctx, queries, note, despues, pinnedFirst)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidNeighbour, err)
+	}
+
+	position, err := rank.Between(before, after)
+	if err != nil {
+		return err
+	}
+
+	return queries.MoveNote(ctx, db.MoveNoteParams{
+		ID:       note.ID,
+		Position: position,
+	})
+}
+
+// neighbourPosition devuelve la posicion de la nota con el id dado, o vacio si
+// no se paso ningun id (principio o final de la lista) o si la vecina es de
+// otro grupo que note.
+func neighbourPosition(ctx context.Context, queries *db.Queries, note db.Note, idStr string, pinnedFirst bool) (string, error) {
+	if idStr == "" {
+		return "", nil
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	neighbour, err := queries.GetNote(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if pinnedFirst && neighbour.PinnedAt.Valid != note.PinnedAt.Valid {
+		return "", nil
+	}
+	return neighbour.Position, nil
+}
+
+// moveToTop pone la nota id antes que todas las demas. Se usa al crear una
+// nota, en la misma transaccion que la inserta: como el INSERT ya tomo el
+// bloqueo de escritura de SQLite, dos notas creadas a la vez no pueden leer la
+// misma primera posicion y quedar con la misma clave.
+func moveToTop(ctx context.Context, queries *db.Queries, id int64) error {
+	position := rank.First
+	first, err := queries.GetFirstNotePosition(ctx, id)
+	if err == nil {
+		position, err = rank.Between("", first)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return queries.MoveNote(ctx, db.MoveNoteParams{
+		ID:       id,
+		Position: position,
+	})
+}
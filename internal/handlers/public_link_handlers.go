@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contraseña de los enlaces publicos: despues de publicLinkMaxAttempts intentos
+// fallidos el enlace no acepta contraseñas durante publicLinkLockout. Al
+// acertarla se guarda una cookie que evita pedirla otra vez durante
+// publicLinkSession.
+const (
+	publicLinkMaxAttempts = 5
+	publicLinkLockout     = 15 * time.Minute
+	publicLinkSession     = time.Hour
+)
+
+// publicLinkCookie es el nombre de la cookie que recuerda la contraseña. Su
+// Path es el del enlace, asi que cada enlace tiene la suya.
+const publicLinkCookie = "publica"
+
+// PublicLink es un enlace publico de una nota junto con su URL completa.
+type PublicLink struct {
+	db.PublicLink
+	URL string
+}
+
+// Expired indica si el enlace tiene fecha de expiracion y ya paso.
+func (l PublicLink) Expired() bool {
+	return l.ExpiresAt.Valid && l.ExpiresAt.Time.Before(time.Now())
+}
+
+// requestScheme devuelve "https" si la peticion llego por HTTPS (directamente o
+// a traves de un proxy) y "http" si no.
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
+
+// PublicLinksHandler muestra los enlaces publicos de una nota y el formulario
+// para crear uno nuevo. Solo lo puede ver el dueño de la nota.
+func PublicLinksHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	list, err := queries.ListPublicLinksForNote(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener los enlaces públicos", http.StatusInternalServerError)
+		return
+	}
+
+	links := make([]PublicLink, 0, len(list))
+	for _, link := range list {
+		links = append(links, PublicLink{
+			PublicLink: link,
+			URL:        requestScheme(r) + "://" + r.Host + "/publica/" + link.Token,
+		})
+	}
+
+	data := map[string]any{
+		"Note":  note,
+		"Links": links,
+	}
+
+	Render(tpl, w, r, "enlaces_publicos.html", data)
+}
+
+// CreatePublicLinkHandler crea un enlace publico para una nota, con una fecha
+// de expiracion y una contraseña opcionales.
+func CreatePublicLinkHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+	if note.DeletedAt.Valid {
+		http.Error(w, "La nota está en la papelera", http.StatusConflict)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	back := "/enlaces_publicos/" + strconv.FormatInt(id, 10)
+
+	var expiresAt sql.NullTime
+	if value := r.FormValue("expira"); value != "" {
+		t, err := time.ParseInLocation(dueAtLayout, value, time.Local)
+		if err != nil {
+			http.Error(w, "Fecha de expiración inválida", http.StatusBadRequest)
+			return
+		}
+		if t.Before(time.Now()) {
+			flash.Push(r, flash.Warning, "La fecha de expiración ya pasó")
+			http.Redirect(w, r, back, http.StatusSeeOther)
+			return
+		}
+		expiresAt = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+
+	var passwordHash sql.NullString
+	if password := r.FormValue("contrasena"); password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "Error al guardar la contraseña", http.StatusInternalServerError)
+			return
+		}
+		passwordHash = sql.NullString{String: string(hash), Valid: true}
+	}
+
+	// El token es aleatorio, igual que el del calendario: no se puede adivinar
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		http.Error(w, "Error al generar el enlace", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = queries.CreatePublicLink(r.Context(), db.CreatePublicLinkParams{
+		NoteID:       id,
+		Token:        hex.EncodeToString(buf),
+		PasswordHash: passwordHash,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		http.Error(w, "Error al crear el enlace", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Enlace público creado")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// RevokePublicLinkHandler elimina un enlace publico; deja de funcionar en el momento.
+func RevokePublicLinkHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	link, err := queries.GetPublicLink(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "El enlace no existe", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener el enlace", http.StatusInternalServerError)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, link.NoteID, accessOwner); !ok {
+		return
+	}
+
+	if err := queries.DeletePublicLink(r.Context(), id); err != nil {
+		http.Error(w, "Error al revocar el enlace", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Enlace revocado")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PublicNoteHandler muestra una nota en solo lectura a quien tenga el enlace,
+// sin iniciar sesion. Si el enlace tiene contraseña se pide antes (GET) y se
+// verifica al enviarla (POST); despues queda recordada en una cookie. Cada vez
+// que se muestra la nota se cuenta una visita.
+func PublicNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries, secret []byte) {
+	// El token va en la URL: no se envia a otros sitios ni se indexa la pagina
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Cache-Control", "no-store")
+
+	token := chi.URLParam(r, "token")
+	link, err := queries.GetPublicLinkByToken(r.Context(), token)
+	if errors.Is(err, sql.ErrNoRows) {
+		renderPublicNote(w, tpl, http.StatusNotFound, map[string]any{
+			"Error": "Este enlace no existe o fue revocado.",
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener el enlace", http.StatusInternalServerError)
+		return
+	}
+	if (PublicLink{PublicLink: link}).Expired() {
+		renderPublicNote(w, tpl, http.StatusGone, map[string]any{
+			"Error": "Este enlace expiró.",
+		})
+		return
+	}
+
+	// Las notas en la papelera no se muestran, pero el enlace vuelve a funcionar si se restauran
+	original, err := queries.GetNote(r.Context(), link.NoteID)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+	if original.DeletedAt.Valid {
+		renderPublicNote(w, tpl, http.StatusNotFound, map[string]any{
+			"Error": "Esta nota ya no está disponible.",
+		})
+		return
+	}
+
+	if link.PasswordHash.Valid && !validPublicLinkCookie(r, link, secret) {
+		if r.Method != http.MethodPost {
+			renderPublicNote(w, tpl, http.StatusOK, map[string]any{
+				"PideContrasena": true,
+			})
+			return
+		}
+		// Mientras el enlace esta bloqueado ni siquiera se compara la contraseña
+		if link.LockedUntil.Valid && link.LockedUntil.Time.After(time.Now()) {
+			renderPublicNote(w, tpl, http.StatusTooManyRequests, map[string]any{
+				"PideContrasena": true,
+				"Error":          "Demasiados intentos fallidos. Prueba de nuevo en unos minutos.",
+			})
+			return
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash.String), []byte(r.PostFormValue("contrasena")))
+		if err != nil {
+			err = queries.FailPublicLinkPassword(r.Context(), db.FailPublicLinkPasswordParams{
+				MaxAttempts: publicLinkMaxAttempts,
+				LockedUntil: sql.NullTime{Time: time.Now().Add(publicLinkLockout).UTC(), Valid: true},
+				ID:          link.ID,
+			})
+			if err != nil {
+				log.Printf("Error contando el intento fallido del enlace %d: %v", link.ID, err)
+			}
+			renderPublicNote(w, tpl, http.StatusUnauthorized, map[string]any{
+				"PideContrasena": true,
+				"Error":          "La contraseña no es correcta.",
+			})
+			return
+		}
+		if link.FailedAttempts > 0 || link.LockedUntil.Valid {
+			if err := queries.ResetPublicLinkAttempts(r.Context(), link.ID); err != nil {
+				log.Printf("Error reiniciando los intentos del enlace %d: %v", link.ID, err)
+			}
+		}
+		setPublicLinkCookie(w, r, link, secret)
+	}
+
+	note, err := getNoteWithTags(r.Context(), queries, link.NoteID, 0)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	if err := queries.CountPublicLinkView(r.Context(), link.ID); err != nil {
+		log.Printf("Error contando la visita del enlace %d: %v", link.ID, err)
+	}
+
+	renderPublicNote(w, tpl, http.StatusOK, map[string]any{
+		"Note": note,
+	})
+}
+
+// publicLinkSignature firma la cookie de un enlace con contraseña. Incluye el
+// hash de la contraseña, asi la cookie deja de valer si el enlace se revoca y
+// se vuelve a crear con otra.
+func publicLinkSignature(link db.PublicLink, expires int64, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("publica:" + link.Token + ":" + strconv.FormatInt(expires, 10) + ":" + link.PasswordHash.String))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setPublicLinkCookie recuerda que ya se ingreso la contraseña del enlace.
+func setPublicLinkCookie(w http.ResponseWriter, r *http.Request, link db.PublicLink, secret []byte) {
+	expires := time.Now().Add(publicLinkSession).Unix()
+	http.SetCookie(w, &http.Cookie{
+		Name:     publicLinkCookie,
+		Value:    strconv.FormatInt(expires, 10) + "." + publicLinkSignature(link, expires, secret),
+		Path:     "/publica/" + link.Token,
+		MaxAge:   int(publicLinkSession.Seconds()),
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// validPublicLinkCookie indica si la peticion trae una cookie vigente del enlace.
+func validPublicLinkCookie(r *http.Request, link db.PublicLink, secret []byte) bool {
+	cookie, err := r.Cookie(publicLinkCookie)
+	if err != nil {
+		return false
+	}
+	expiresStr, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(publicLinkSignature(link, expires, secret)))
+}
+
+// renderPublicNote renderiza la pagina publica, que no usa el layout de la aplicacion.
+func renderPublicNote(w http.ResponseWriter, tpl *template.Template, status int, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tpl.ExecuteTemplate(w, "publica.html", data); err != nil {
+		log.Printf("Error renderizando: %v", err)
+	}
+}
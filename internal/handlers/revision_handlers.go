@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/diff"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// RevisionDiff es el diff entre una revision y la version que la reemplazo.
+type RevisionDiff struct {
+	NombreAntes   string
+	NombreDespues string
+	Lines         []diff.Line
+}
+
+// saveRevision guarda la version actual de la nota en el historial.
+func saveRevision(ctx context.Context, queries *db.Queries, note db.Note) error {
+	_, err := queries.CreateNoteRevision(ctx, db.CreateNoteRevisionParams{
+		NoteID:    note.ID,
+		Nombre:    note.Nombre,
+		Contenido: note.Contenido,
+		EditedAt:  note.UpdatedAt,
+	})
+	return err
+}
+
+// NoteHistoryHandler muestra el historial de revisiones de una nota.
+func NoteHistoryHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, access, ok := authorizeNote(w, r, queries, id, accessRead)
+	if !ok {
+		return
+	}
+
+	revisions, err := queries.ListNoteRevisions(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener el historial", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Note":      note,
+		"Revisions": revisions,
+		// Solo quien puede editar la nota puede restaurar una revision
+		"PuedeEditar": access >= accessEdit,
+	}
+
+	Render(tpl, w, r, "historial_nota.html", data)
+}
+
+// RevisionDiffHandler devuelve el fragmento con el diff entre una revision y la
+// version siguiente (la revision posterior o, si no hay, la version actual).
+func RevisionDiffHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := queries.GetNoteRevision(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener la revisión", http.StatusInternalServerError)
+		return
+	}
+	if _, _, ok := authorizeNote(w, r, queries, revision.NoteID, accessRead); !ok {
+		return
+	}
+
+	var nombreDespues, contenidoDespues string
+	next, err := queries.GetNextNoteRevision(r.Context(), db.GetNextNoteRevisionParams{
+		NoteID: revision.NoteID,
+		ID:     revision.ID,
+	})
+	switch {
+	case err == nil:
+		nombreDespues, contenidoDespues = next.Nombre, next.Contenido.String
+	case errors.Is(err, sql.ErrNoRows):
+		// Es la ultima revision, se compara con la version actual de la nota
+		note, err := queries.GetNote(r.Context(), revision.NoteID)
+		if err != nil {
+			http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+			return
+		}
+		nombreDespues, contenidoDespues = note.Nombre, note.Contenido.String
+	default:
+		http.Error(w, "Error al obtener la revisión siguiente", http.StatusInternalServerError)
+		return
+	}
+
+	RenderPartial(tpl, w, "diff_revision.html", RevisionDiff{
+		NombreAntes:   revision.Nombre,
+		NombreDespues: nombreDespues,
+		Lines:         diff.Lines(revision.Contenido.String, contenidoDespues),
+	})
+}
+
+// RestoreRevisionHandler restaura una revision. La version actual se guarda antes
+// en el historial, por lo que restaurar tambien crea una nueva revision.
+func RestoreRevisionHandler(w http.ResponseWriter, r *http.Request, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := queries.GetNoteRevision(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener la revisión", http.StatusInternalServerError)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, revision.NoteID, accessEdit)
+	if !ok {
+		return
+	}
+
+	tx, err := conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Error al restaurar la nota", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if err := saveRevision(r.Context(), qtx, note); err != nil {
+		http.Error(w, "Error al guardar el historial de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := qtx.UpdateNote(r.Context(), db.UpdateNoteParams{
+		ID:        note.ID,
+		Nombre:    revision.Nombre,
+		Contenido: revision.Contenido,
+		Version:   note.Version,
+	})
+	if err != nil {
+		http.Error(w, "Error al restaurar la nota", http.StatusInternalServerError)
+		return
+	}
+	// El rollback descarta la revision guardada para este intento
+	if updated == 0 {
+		tx.Rollback()
+		http.Error(w, "La nota fue modificada mientras se restauraba, inténtalo de nuevo", http.StatusConflict)
+		return
+	}
+
+	err = noteSaved(r.Context(), qtx, renderer, note.ID, note.Nombre, revision.Nombre, revision.Contenido.String)
+	if err != nil {
+		http.Error(w, "Error al guardar los enlaces de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error al restaurar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Versión restaurada")
+	http.Redirect(w, r, "/historial_nota/"+strconv.FormatInt(note.ID, 10), http.StatusFound)
+}
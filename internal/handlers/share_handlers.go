@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// noteAccess es lo que un usuario puede hacer con una nota, de menor a mayor.
+type noteAccess int
+
+const (
+	accessNone noteAccess = iota
+	// accessRead permite ver la nota, su historial y sus adjuntos.
+	accessRead
+	// accessEdit permite ademas modificar el contenido, las tareas y los adjuntos.
+	accessEdit
+	// accessOwner permite todo, incluido borrar, archivar y compartir la nota.
+	accessOwner
+)
+
+// Permisos con los que se comparte una nota (columna note_shares.permission).
+const (
+	sharePermissionRead = "lectura"
+	sharePermissionEdit = "edicion"
+)
+
+// sharePermissions son los permisos que se pueden elegir al compartir.
+var sharePermissions = []struct {
+	Value string
+	Label string
+}{
+	{sharePermissionRead, "Solo lectura"},
+	{sharePermissionEdit, "Lectura y edición"},
+}
+
+// ownedBy es el valor de notes.user_id para las notas del usuario.
+func ownedBy(userID int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: userID, Valid: true}
+}
+
+// accessToNote devuelve el acceso del usuario a la nota: total si es el dueño
+// de una nota personal; en las notas de un espacio de trabajo, el que le da su
+// rol; y si se la compartieron, el mayor entre ese y el permiso compartido.
+func accessToNote(ctx context.Context, queries *db.Queries, note db.Note, userID int64) (noteAccess, error) {
+	access := accessNone
+	if note.WorkspaceID.Valid {
+		var err error
+		access, err = workspaceAccess(ctx, queries, note, userID)
+		if err != nil {
+			return accessNone, err
+		}
+	} else if note.UserID.Valid && note.UserID.Int64 == userID {
+		access = accessOwner
+	}
+	if access == accessOwner {
+		return access, nil
+	}
+
+	share, err := queries.GetNoteShare(ctx, db.GetNoteShareParams{NoteID: note.ID, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return access, nil
+	}
+	if err != nil {
+		return accessNone, err
+	}
+	if share.Permission == sharePermissionEdit {
+		return max(access, accessEdit), nil
+	}
+	return max(access, accessRead), nil
+}
+
+// authorizeNote obtiene la nota id y verifica que el usuario autenticado tenga
+// al menos el acceso need. Si no lo tiene escribe el error y devuelve false: 404
+// si no puede ver la nota, para no revelar que existe, y 403 si puede verla.
+func authorizeNote(w http.ResponseWriter, r *http.Request, queries *db.Queries, id int64, need noteAccess) (db.Note, noteAccess, bool) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return db.Note{}, accessNone, false
+	}
+
+	note, err := queries.GetNote(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "La nota no existe", http.StatusNotFound)
+		return db.Note{}, accessNone, false
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return db.Note{}, accessNone, false
+	}
+
+	access, err := accessToNote(r.Context(), queries, note, user.ID)
+	if err != nil {
+		http.Error(w, "Error al verificar los permisos de la nota", http.StatusInternalServerError)
+		return db.Note{}, accessNone, false
+	}
+	if access == accessNone {
+		http.Error(w, "La nota no existe", http.StatusNotFound)
+		return db.Note{}, accessNone, false
+	}
+	if access < need {
+		http.Error(w, "No tienes permiso para hacer esto con la nota", http.StatusForbidden)
+		return db.Note{}, accessNone, false
+	}
+	return note, access, true
+}
+
+// setSharedAccess marca la nota como compartida con el usuario actual, para
+// que su tarjeta muestre solo las acciones permitidas. No hace nada si es el dueño.
+func setSharedAccess(ctx context.Context, queries *db.Queries, note *NoteWithTags, access noteAccess, source db.Note) error {
+	note.EnEspacio = source.WorkspaceID.Valid
+	if access == accessOwner {
+		return nil
+	}
+	ownerID := source.UserID
+
+	note.Compartida = sharePermissionRead
+	if access == accessEdit {
+		note.Compartida = sharePermissionEdit
+	}
+	owner, err := queries.GetUser(ctx, ownerID.Int64)
+	if err != nil {
+		return err
+	}
+	note.Duenio = owner.Username
+	return nil
+}
+
+// SharedNotesHandler muestra las notas que otros usuarios compartieron con el usuario actual.
+func SharedNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	shared, err := queries.ListNotesSharedWith(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Error al obtener las notas compartidas", http.StatusInternalServerError)
+		return
+	}
+
+	notes := make([]*NoteWithTags, 0, len(shared))
+	for _, s := range shared {
+		note, err := getNoteWithTags(r.Context(), queries, s.ID, user.ID)
+		if err != nil {
+			http.Error(w, "Error al obtener las notas compartidas", http.StatusInternalServerError)
+			return
+		}
+		note.Compartida = s.Permission
+		note.Duenio = s.OwnerUsername
+		notes = append(notes, note)
+	}
+
+	data := map[string]any{
+		"Notes": notes,
+	}
+
+	Render(tpl, w, r, "compartidas.html", data)
+}
+
+// ShareNoteFormHandler muestra con quien esta compartida una nota y el
+// formulario para compartirla con otro usuario. Solo lo puede ver el dueño.
+func ShareNoteFormHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	shares, err := queries.ListSharesForNote(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener con quién está compartida la nota", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Note":     note,
+		"Shares":   shares,
+		"Permisos": sharePermissions,
+	}
+
+	Render(tpl, w, r, "compartir_nota.html", data)
+}
+
+// ShareNoteHandler comparte una nota con otro usuario. Si ya estaba compartida
+// con el se actualiza el permiso.
+func ShareNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	back := "/compartir_nota/" + strconv.FormatInt(id, 10)
+	username := strings.TrimSpace(r.FormValue("usuario"))
+	permission := r.FormValue("permiso")
+	if permission != sharePermissionRead && permission != sharePermissionEdit {
+		http.Error(w, "Permiso inválido", http.StatusBadRequest)
+		return
+	}
+
+	user, err := queries.GetUserByUsername(r.Context(), username)
+	if errors.Is(err, sql.ErrNoRows) {
+		flash.Push(r, flash.Error, "No existe el usuario \""+username+"\"")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al buscar el usuario", http.StatusInternalServerError)
+		return
+	}
+	if note.UserID.Valid && user.ID == note.UserID.Int64 {
+		flash.Push(r, flash.Warning, "No hace falta compartir la nota contigo")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	err = queries.ShareNote(r.Context(), db.ShareNoteParams{
+		NoteID:     id,
+		UserID:     user.ID,
+		Permission: permission,
+	})
+	if err != nil {
+		http.Error(w, "Error al compartir la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota compartida con "+user.Username)
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// UnshareNoteHandler deja de compartir una nota con un usuario.
+func UnshareNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, id, accessOwner); !ok {
+		return
+	}
+
+	user, err := queries.GetUserByUsername(r.Context(), chi.URLParam(r, "usuario"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "El usuario no existe", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al buscar el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	removed, err := queries.UnshareNote(r.Context(), db.UnshareNoteParams{NoteID: id, UserID: user.ID})
+	if err != nil {
+		http.Error(w, "Error al dejar de compartir la nota", http.StatusInternalServerError)
+		return
+	}
+	if removed == 0 {
+		http.Error(w, "La nota no estaba compartida con ese usuario", http.StatusNotFound)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Ya no compartes la nota con "+user.Username)
+	w.WriteHeader(http.StatusOK)
+}
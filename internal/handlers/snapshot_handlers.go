@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/snapshot"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// SnapshotsHandler muestra las copias de la base de datos.
+func SnapshotsHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, snapshots *snapshot.Manager) {
+	list, err := snapshots.List()
+	if err != nil {
+		http.Error(w, "Error al obtener las copias", http.StatusInternalServerError)
+		return
+	}
+
+	Render(tpl, w, r, "copias.html", map[string]any{
+		"Snapshots": list,
+		"Keep":      snapshots.Keep,
+		"Dias":      int(snapshots.MaxAge.Hours() / 24),
+	})
+}
+
+// CreateSnapshotHandler hace una copia de la base de datos en el momento.
+func CreateSnapshotHandler(w http.ResponseWriter, r *http.Request, conn *sql.DB, snapshots *snapshot.Manager) {
+	s, err := snapshots.Create(r.Context(), conn)
+	if err != nil {
+		log.Printf("Error creando copia: %v", err)
+		flash.Push(r, flash.Error, "No se pudo crear la copia: "+err.Error())
+	} else {
+		flash.Push(r, flash.Success, "Copia creada: "+s.Name)
+	}
+	http.Redirect(w, r, "/admin/copias", http.StatusSeeOther)
+}
+
+// DownloadSnapshotHandler descarga una copia de la base de datos.
+func DownloadSnapshotHandler(w http.ResponseWriter, r *http.Request, snapshots *snapshot.Manager) {
+	s, err := snapshots.Get(chi.URLParam(r, "nombre"))
+	if errors.Is(err, snapshot.ErrNotFound) {
+		http.Error(w, "La copia no existe", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener la copia", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+s.Name+`"`)
+	http.ServeFile(w, r, s.Path)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeAgo devuelve una descripcion relativa de t respecto al momento actual (ej. "hace 5 minutos").
+// Las fechas de mas de una semana se muestran como fecha absoluta.
+func TimeAgo(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "hace un momento"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "hace 1 minuto", "hace %d minutos")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hace 1 hora", "hace %d horas")
+	case d < 48*time.Hour:
+		return "ayer"
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("hace %d días", int(d.Hours()/24))
+	default:
+		return "el " + t.Local().Format("02/01/2006")
+	}
+}
+
+// FormatDateTime formatea t como fecha y hora local, para usar en atributos title.
+func FormatDateTime(t time.Time) string {
+	return t.Local().Format("02/01/2006 15:04")
+}
+
+// FormatSize formatea un tamaño en bytes de forma legible (ej. "1.5 MB").
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return fmt.Sprintf(many, n)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/storage"
+	"github.com/Calevin/go_htmx_crud/internal/trash"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TrashedNote es una nota en la papelera junto con la fecha en que se purgara.
+type TrashedNote struct {
+	db.Note
+	PurgeAt time.Time
+}
+
+// TrashHandler muestra las notas del espacio actual que estan en la papelera.
+func TrashHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries, retention time.Duration) {
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	notes, err := queries.ListTrashedNotes(r.Context(), db.ListTrashedNotesParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+	})
+	if err != nil {
+		http.Error(w, "Error al obtener la papelera", http.StatusInternalServerError)
+		return
+	}
+
+	trashed := make([]TrashedNote, 0, len(notes))
+	for _, note := range notes {
+		trashed = append(trashed, TrashedNote{
+			Note:    note,
+			PurgeAt: note.DeletedAt.Time.Add(retention),
+		})
+	}
+
+	data := map[string]any{
+		"Notes": trashed,
+	}
+
+	Render(tpl, w, r, "papelera.html", data)
+}
+
+// RestoreNoteHandler saca una nota de la papelera y devuelve su tarjeta, para
+// reemplazar el aviso de "deshacer" en el listado de notas.
+func RestoreNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, _, ok := authorizeNote(w, r, queries, id, accessOwner); !ok {
+		return
+	}
+
+	err = queries.RestoreNote(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al restaurar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	note, err := getNoteForRequest(r, queries, id)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota restaurada")
+	RenderPartial(tpl, w, "nota_card.html", note)
+}
+
+// PurgeNoteHandler borra definitivamente una nota de la papelera junto con sus adjuntos.
+func PurgeNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries, store storage.BlobStore) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	// Solo se pueden eliminar definitivamente las notas que estan en la papelera
+	if !note.DeletedAt.Valid {
+		http.Error(w, "La nota no está en la papelera", http.StatusConflict)
+		return
+	}
+
+	err = trash.DeleteNote(r.Context(), queries, store, id)
+	if err != nil {
+		http.Error(w, "Error al eliminar la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota eliminada definitivamente")
+	w.WriteHeader(http.StatusOK)
+}
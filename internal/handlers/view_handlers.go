@@ -1,28 +1,220 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/diff"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
 	"github.com/go-chi/chi/v5"
 	"html/template"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Estructura para pasar datos enriquecidos al template
 type NoteWithTags struct {
-	ID        int64
-	Nombre    string
-	Contenido string
-	Tags      []db.Tag
+	ID          int64
+	Nombre      string
+	Contenido   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     int64
+	PinnedAt    sql.NullTime
+	ArchivedAt  sql.NullTime
+	Position    string
+	DueAt       sql.NullTime
+	Tags        []db.Tag
+	Attachments []db.Attachment
+	// Backlinks son las notas que enlazan a esta con [[...]]
+	Backlinks []LinkedNote
+	// Compartida es el permiso con el que otro usuario compartio la nota con el
+	// usuario actual ("lectura" o "edicion"), o el que le da su rol en el
+	// espacio de trabajo; vacio si puede hacer todo con la nota.
+	Compartida string
+	// Duenio es el nombre del usuario que compartio o creo la nota.
+	Duenio string
+	// EnEspacio indica si la nota es de un espacio de trabajo.
+	EnEspacio bool
 }
 
-// Render rederiza dentro de layout el template contentFile con los datos pasados como parametros
-func Render(tpl *template.Template, w http.ResponseWriter, contentFile string, data any) {
+// Overdue indica si la nota tiene fecha de vencimiento y ya paso.
+func (n NoteWithTags) Overdue() bool {
+	return n.DueAt.Valid && n.DueAt.Time.Before(time.Now())
+}
+
+// Ordenes disponibles para el listado de notas.
+var noteOrders = []struct {
+	Value string
+	Label string
+}{
+	{"manual", "Orden personalizado"},
+	{"recientes", "Más recientes"},
+	{"antiguas", "Más antiguas"},
+	{"editadas", "Editadas recientemente"},
+}
+
+// parseNoteOrder devuelve el orden pedido en la query o el orden por defecto si no es válido.
+func parseNoteOrder(r *http.Request) string {
+	orden := r.URL.Query().Get("orden")
+	for _, o := range noteOrders {
+		if o.Value == orden {
+			return orden
+		}
+	}
+	return noteOrders[0].Value
+}
+
+// sortNotes ordena las notas segun el orden elegido. La consulta ya las devuelve
+// de la mas nueva a la mas vieja, por eso se usa un orden estable.
+// Las notas fijadas siempre quedan primero, sin importar el orden elegido.
+func sortNotes(notes []*NoteWithTags, orden string) {
+	switch orden {
+	case "manual":
+		sort.SliceStable(notes, func(i, j int) bool {
+			return notes[i].Position < notes[j].Position
+		})
+	case "antiguas":
+		sort.SliceStable(notes, func(i, j int) bool {
+			return notes[i].CreatedAt.Before(notes[j].CreatedAt)
+		})
+	case "editadas":
+		sort.SliceStable(notes, func(i, j int) bool {
+			return notes[i].UpdatedAt.After(notes[j].UpdatedAt)
+		})
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].PinnedAt.Valid && !notes[j].PinnedAt.Valid
+	})
+}
+
+// noteFilter son los filtros del listado de notas, tomados de la query.
+type noteFilter struct {
+	// Busqueda filtra por texto en el nombre o el contenido. Cuando hay una
+	// busqueda tambien se incluyen las notas archivadas.
+	Busqueda string
+	// Archivadas muestra solo las notas archivadas (vista de archivo).
+	Archivadas bool
+}
+
+// parseNoteFilter lee los filtros del listado desde la query.
+func parseNoteFilter(r *http.Request, archivadas bool) noteFilter {
+	return noteFilter{
+		Busqueda:   strings.TrimSpace(r.URL.Query().Get("q")),
+		Archivadas: archivadas,
+	}
+}
+
+// filterNotes devuelve las notas que cumplen con el filtro, manteniendo el orden.
+func filterNotes(notes []*NoteWithTags, filter noteFilter) []*NoteWithTags {
+	busqueda := strings.ToLower(filter.Busqueda)
+	filtered := make([]*NoteWithTags, 0, len(notes))
+	for _, note := range notes {
+		if filter.Archivadas && !note.ArchivedAt.Valid {
+			continue
+		}
+		// Las notas archivadas no se listan, pero siguen apareciendo en las busquedas
+		if !filter.Archivadas && note.ArchivedAt.Valid && busqueda == "" {
+			continue
+		}
+		if busqueda != "" &&
+			!strings.Contains(strings.ToLower(note.Nombre), busqueda) &&
+			!strings.Contains(strings.ToLower(note.Contenido), busqueda) {
+			continue
+		}
+		filtered = append(filtered, note)
+	}
+	return filtered
+}
+
+// getNoteForRequest obtiene una nota como la ve el usuario autenticado.
+func getNoteForRequest(r *http.Request, queries *db.Queries, id int64) (*NoteWithTags, error) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		return nil, err
+	}
+	return getNoteWithTags(r.Context(), queries, id, user.ID)
+}
+
+// getNoteWithTags obtiene una nota junto con sus tags. De las notas que la
+// enlazan solo se incluyen las que viewerID puede ver; con viewerID 0, como en
+// los enlaces publicos, no se incluye ninguna.
+func getNoteWithTags(ctx context.Context, queries *db.Queries, id, viewerID int64) (*NoteWithTags, error) {
+	note, err := queries.GetNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := queries.GetTagsForNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := queries.ListAttachmentsForNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var backlinks []LinkedNote
+	if viewerID != 0 {
+		sources, err := queries.ListBacklinks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			if source.DeletedAt.Valid {
+				continue
+			}
+			access, err := accessToNote(ctx, queries, source, viewerID)
+			if err != nil {
+				return nil, err
+			}
+			if access > accessNone {
+				backlinks = append(backlinks, LinkedNote{ID: source.ID, Nombre: source.Nombre})
+			}
+		}
+	}
+
+	return &NoteWithTags{
+		ID:          note.ID,
+		Nombre:      note.Nombre,
+		Contenido:   note.Contenido.String,
+		CreatedAt:   note.CreatedAt,
+		UpdatedAt:   note.UpdatedAt,
+		Version:     note.Version,
+		PinnedAt:    note.PinnedAt,
+		ArchivedAt:  note.ArchivedAt,
+		Position:    note.Position,
+		DueAt:       note.DueAt,
+		Tags:        tags,
+		Attachments: attachments,
+		Backlinks:   backlinks,
+	}, nil
+}
+
+// RenderPartial renderiza solo el template contentFile, sin layout. Se usa para
+// responder fragmentos a peticiones HTMX.
+func RenderPartial(tpl *template.Template, w http.ResponseWriter, contentFile string, data any) {
+	err := tpl.ExecuteTemplate(w, contentFile, data)
+	if err != nil {
+		log.Printf("Error renderizando: %v", err)
+		http.Error(w, "Error del servidor", 500)
+	}
+}
+
+// Render rederiza dentro de layout el template contentFile con los datos pasados como parametros.
+// Tambien se incluyen las notificaciones pendientes para mostrarlas como toasts.
+func Render(tpl *template.Template, w http.ResponseWriter, r *http.Request, contentFile string, data any) {
 	err := tpl.ExecuteTemplate(w, "layout.html", map[string]any{
 		"contentFile": contentFile,
 		"data":        data,
+		"flashes":     flash.Consume(w, r),
+		"espacios":    currentWorkspaces(r),
 	})
 	if err != nil {
 		log.Printf("Error renderizando: %v", err)
@@ -30,15 +222,19 @@ func Render(tpl *template.Template, w http.ResponseWriter, contentFile string, d
 	}
 }
 
-// ListNotesHandler muestra la lista de notas del usuario
-func ListNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
-	// Lógica para obtener las notas
-	notesWithTagsFromDB, err := queries.ListNotesWithTags(r.Context())
+// listNotes obtiene todas las notas del espacio que no estan en la papelera, con sus tags y adjuntos.
+func listNotes(ctx context.Context, queries *db.Queries, scope noteScope) ([]*NoteWithTags, error) {
+	notesWithTagsFromDB, err := queries.ListNotesWithTags(ctx, db.ListNotesWithTagsParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+	})
 	if err != nil {
-		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
+	// Nombres de los autores de las notas del espacio, para no buscarlos en cada nota
+	usernames := make(map[int64]string)
+
 	// Mapa para no duplicar notas y agrupar sus tags.
 	notesMap := make(map[int64]*NoteWithTags)
 	// Slice para mantener el orden original.
@@ -48,10 +244,20 @@ func ListNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Temp
 		// Si no existe se agrega al mapa
 		if _, ok := notesMap[noteAndTag.NoteID]; !ok {
 			note := &NoteWithTags{
-				ID:        noteAndTag.NoteID,
-				Nombre:    noteAndTag.NoteNombre,
-				Contenido: noteAndTag.NoteContenido.String,
-				Tags:      []db.Tag{}, // Se inicializa el slice de tags vacío.
+				ID:         noteAndTag.NoteID,
+				Nombre:     noteAndTag.NoteNombre,
+				Contenido:  noteAndTag.NoteContenido.String,
+				CreatedAt:  noteAndTag.NoteCreatedAt,
+				UpdatedAt:  noteAndTag.NoteUpdatedAt,
+				Version:    noteAndTag.NoteVersion,
+				PinnedAt:   noteAndTag.NotePinnedAt,
+				ArchivedAt: noteAndTag.NoteArchivedAt,
+				Position:   noteAndTag.NotePosition,
+				DueAt:      noteAndTag.NoteDueAt,
+				Tags:       []db.Tag{}, // Se inicializa el slice de tags vacío.
+			}
+			if err := setWorkspaceAccess(ctx, queries, note, scope, noteAndTag.NoteUserID, usernames); err != nil {
+				return nil, err
 			}
 			// se agrega al mapa y al lista ordenada
 			notesMap[noteAndTag.NoteID] = note
@@ -68,15 +274,85 @@ func ListNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Temp
 			notesMap[noteAndTag.NoteID].Tags = append(notesMap[noteAndTag.NoteID].Tags, tag)
 		}
 	}
+	// Se agregan los adjuntos a cada nota
+	attachments, err := queries.ListAttachments(ctx, db.ListAttachmentsParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		if note, ok := notesMap[attachment.NoteID]; ok {
+			note.Attachments = append(note.Attachments, attachment)
+		}
+	}
+	// Y las notas del espacio que las enlazan
+	backlinks, err := queries.ListAllBacklinks(ctx, db.ListAllBacklinksParams{
+		WorkspaceID: scope.WorkspaceID,
+		UserID:      ownedBy(scope.UserID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range backlinks {
+		if note, ok := notesMap[link.TargetID]; ok {
+			note.Backlinks = append(note.Backlinks, LinkedNote{ID: link.SourceID, Nombre: link.SourceNombre})
+		}
+	}
+
+	return orderedNotes, nil
+}
+
+// ListNotesHandler muestra la lista de notas del espacio actual del usuario
+func ListNotesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	orden := parseNoteOrder(r)
+	filter := parseNoteFilter(r, false)
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	// Lógica para obtener las notas
+	notes, err := listNotes(r.Context(), queries, scope)
+	if err != nil {
+		http.Error(w, "Error al obtener notas", http.StatusInternalServerError)
+		return
+	}
+
+	notes = filterNotes(notes, filter)
+	sortNotes(notes, orden)
+
 	// Se renderiza la página de notas, pasando los datos.
 	data := make(map[string]any)
-	data["Notes"] = orderedNotes
-	Render(tpl, w, "notas.html", data)
+	data["Notes"] = notes
+	data["Orden"] = orden
+	data["Ordenes"] = noteOrders
+	data["Busqueda"] = filter.Busqueda
+	data["PuedeCrear"] = scope.CanWrite()
+	if workspaces := currentWorkspaces(r); workspaces != nil && workspaces.Current != nil {
+		data["Espacio"] = workspaces.Current.Nombre
+	}
+	// La exportacion a CSV usa los mismos filtros que el listado
+	data["ExportarCSV"] = "/exportar.csv?" + r.URL.RawQuery
+	Render(tpl, w, r, "notas.html", data)
 }
 
 // CreateNoteFormHandler muestra el formulario para crear una nueva nota.
 func CreateNoteFormHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
-	tags, err := queries.ListTags(r.Context())
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+	if !scope.CanWrite() {
+		http.Error(w, "Tu rol en este espacio no permite crear notas", http.StatusForbidden)
+		return
+	}
+
+	tags, err := queries.ListTags(r.Context(), scope.WorkspaceID)
 	if err != nil {
 		log.Printf("Error obteniendo tags: %v", err)
 		http.Error(w, "Error del servidor", 500)
@@ -87,11 +363,11 @@ func CreateNoteFormHandler(w http.ResponseWriter, r *http.Request, tpl *template
 		"Tags": tags,
 	}
 
-	Render(tpl, w, "crear_nota.html", data)
+	Render(tpl, w, r, "crear_nota.html", data)
 }
 
 // CreateNoteHandler procesa el formulario para crear una nueva nota.
-func CreateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+func CreateNoteHandler(w http.ResponseWriter, r *http.Request, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer, reminderLead time.Duration) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
 		return
@@ -108,19 +384,58 @@ func CreateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queri
 		return
 	}
 
-	note, err := queries.CreateNote(r.Context(), db.CreateNoteParams{
+	dueAt, _, err := parseDueAt(r)
+	if err != nil {
+		http.Error(w, "Fecha de vencimiento inválida", http.StatusBadRequest)
+		return
+	}
+
+	scope, err := currentScope(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+	if !scope.CanWrite() {
+		http.Error(w, "Tu rol en este espacio no permite crear notas", http.StatusForbidden)
+		return
+	}
+
+	// El tag tiene que ser del mismo espacio que la nota
+	tag, err := queries.GetTag(r.Context(), tagID)
+	if err != nil || !scope.ContainsTag(tag) {
+		http.Error(w, "ID de tag inválido", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Error al crear la nota", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	note, err := qtx.CreateNote(r.Context(), db.CreateNoteParams{
 		Nombre: nombre,
 		Contenido: sql.NullString{
 			String: contenido,
 			Valid:  true,
 		},
+		UserID:      ownedBy(scope.UserID),
+		WorkspaceID: scope.WorkspaceID,
 	})
 	if err != nil {
 		http.Error(w, "Error al crear la nota", http.StatusInternalServerError)
 		return
 	}
 
-	err = queries.LinkTagToNote(r.Context(), db.LinkTagToNoteParams{
+	// Las notas nuevas se agregan al principio del orden personalizado
+	if err := moveToTop(r.Context(), qtx, note.ID); err != nil {
+		http.Error(w, "Error al calcular la posición de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.LinkTagToNote(r.Context(), db.LinkTagToNoteParams{
 		NoteID: note.ID,
 		TagID:  tagID,
 	})
@@ -130,11 +445,31 @@ func CreateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queri
 		return
 	}
 
+	err = noteSaved(r.Context(), qtx, renderer, note.ID, "", note.Nombre, contenido)
+	if err != nil {
+		http.Error(w, "Error al guardar los enlaces de la nota", http.StatusInternalServerError)
+		return
+	}
+
+	if dueAt.Valid {
+		err = setDueAt(r.Context(), qtx, note.ID, dueAt, reminderLead)
+		if err != nil {
+			http.Error(w, "Error al guardar la fecha de vencimiento", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error al crear la nota", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Nota creada")
 	http.Redirect(w, r, "/notas", http.StatusFound)
 }
 
-// DeleteNoteHandler borra la nota con el id pasado como parametro
-func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+// DeleteNoteHandler envia a la papelera la nota con el id pasado como parametro
+func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -142,13 +477,21 @@ func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queri
 		return
 	}
 
-	err = queries.DeleteNote(r.Context(), id)
+	// Solo el dueño puede borrar la nota, aunque este compartida con permiso de edicion
+	note, _, ok := authorizeNote(w, r, queries, id, accessOwner)
+	if !ok {
+		return
+	}
+
+	// La nota se envia a la papelera, se puede restaurar hasta que se purgue
+	err = queries.TrashNote(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Error al borrar la nota", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	// Se reemplaza la tarjeta por un aviso con el enlace para deshacer
+	RenderPartial(tpl, w, "nota_borrada.html", note)
 }
 
 // EditNoteFormHandler muestra el formulario para editar una nota.
@@ -160,26 +503,23 @@ func EditNoteFormHandler(w http.ResponseWriter, r *http.Request, tpl *template.T
 		return
 	}
 
-	note, err := queries.GetNote(r.Context(), id)
-	if err != nil {
-		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+	note, access, ok := authorizeNote(w, r, queries, id, accessEdit)
+	if !ok {
 		return
 	}
 
-	tags, err := queries.GetTagsForNote(r.Context(), id)
+	noteWithTags, err := getNoteForRequest(r, queries, id)
 	if err != nil {
-		http.Error(w, "Error al tag de la nota", http.StatusInternalServerError)
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
 		return
 	}
-
-	noteWithTags := &NoteWithTags{
-		ID:        note.ID,
-		Nombre:    note.Nombre,
-		Contenido: note.Contenido.String,
-		Tags:      tags,
+	if err := setSharedAccess(r.Context(), queries, noteWithTags, access, note); err != nil {
+		http.Error(w, "Error al obtener el dueño de la nota", http.StatusInternalServerError)
+		return
 	}
 
-	allTags, err := queries.ListTags(r.Context())
+	// Solo se puede elegir entre los tags del espacio de la nota
+	allTags, err := queries.ListTags(r.Context(), note.WorkspaceID)
 	if err != nil {
 		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
 		return
@@ -190,11 +530,12 @@ func EditNoteFormHandler(w http.ResponseWriter, r *http.Request, tpl *template.T
 		"Tags": allTags,
 	}
 
-	Render(tpl, w, "editar_nota.html", data)
+	Render(tpl, w, r, "editar_nota.html", data)
 }
 
 // UpdateNoteHandler procesa el formulario de edición de una nota.
-func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+// Si la nota fue modificada desde que se abrió el formulario se muestra la pantalla de conflicto.
+func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, conn *sql.DB, queries *db.Queries, renderer *markdown.Renderer, reminderLead time.Duration) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -217,28 +558,88 @@ func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queri
 		return
 	}
 
-	noteOriginal, err := queries.GetNote(r.Context(), id)
+	// Los formularios sin el campo de vencimiento (ej. la pantalla de conflicto) no lo modifican
+	dueAt, hasDueAt, err := parseDueAt(r)
+	if err != nil {
+		http.Error(w, "Fecha de vencimiento inválida", http.StatusBadRequest)
+		return
+	}
+
+	// Version de la nota sobre la que se hizo la edicion
+	version, err := strconv.ParseInt(r.FormValue("version"), 10, 64)
 	if err != nil {
-		http.Error(w, "Error al obtener la nota original", http.StatusInternalServerError)
+		http.Error(w, "Versión de la nota inválida", http.StatusBadRequest)
+		return
+	}
+
+	noteOriginal, access, ok := authorizeNote(w, r, queries, id, accessEdit)
+	if !ok {
+		return
+	}
+
+	tag, err := queries.GetTag(r.Context(), tagID)
+	if err != nil || !scopeOf(noteOriginal).ContainsTag(tag) {
+		http.Error(w, "ID de tag inválido", http.StatusBadRequest)
+		return
+	}
+
+	// Si la nota cambio desde que se abrio el formulario, no se sobrescribe
+	if noteOriginal.Version != version {
+		renderConflict(w, r, tpl, queries, noteOriginal, nombre, contenido, tagID)
 		return
 	}
 
 	if noteOriginal.Nombre != nombre || noteOriginal.Contenido.String != contenido {
-		err = queries.UpdateNote(r.Context(), db.UpdateNoteParams{
+		// La revision, la nota y sus enlaces se guardan juntos: si hay conflicto
+		// no queda en el historial una revision que no corresponde a ningun cambio
+		tx, err := conn.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "Error al actualizar la nota", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+		qtx := queries.WithTx(tx)
+
+		// Se guarda la version anterior en el historial antes de sobrescribirla
+		err = saveRevision(r.Context(), qtx, noteOriginal)
+		if err != nil {
+			http.Error(w, "Error al guardar el historial de la nota", http.StatusInternalServerError)
+			return
+		}
+
+		updated, err := qtx.UpdateNote(r.Context(), db.UpdateNoteParams{
 			ID:     id,
 			Nombre: nombre,
 			Contenido: sql.NullString{
 				String: contenido,
 				Valid:  true,
 			},
+			Version: version,
 		})
 		if err != nil {
 			http.Error(w, "Error al actualizar la nota", http.StatusInternalServerError)
 			return
 		}
+		// Otra peticion actualizo la nota entre la lectura y la escritura
+		if updated == 0 {
+			tx.Rollback()
+			renderConflict(w, r, tpl, queries, noteOriginal, nombre, contenido, tagID)
+			return
+		}
+
+		err = noteSaved(r.Context(), qtx, renderer, id, noteOriginal.Nombre, nombre, contenido)
+		if err != nil {
+			http.Error(w, "Error al guardar los enlaces de la nota", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Error al actualizar la nota", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	tags, err := queries.ListTags(r.Context())
+	tags, err := queries.ListTags(r.Context(), noteOriginal.WorkspaceID)
 	if err != nil {
 		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
 		return
@@ -261,5 +662,51 @@ func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, queries *db.Queri
 		}
 	}
 
+	if hasDueAt && !sameDueAt(dueAt, noteOriginal.DueAt) {
+		err = setDueAt(r.Context(), queries, id, dueAt, reminderLead)
+		if err != nil {
+			http.Error(w, "Error al guardar la fecha de vencimiento", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	flash.Push(r, flash.Success, "Nota actualizada")
+	// Las notas compartidas no estan en el listado del usuario, se vuelve a la nota
+	if access != accessOwner {
+		http.Redirect(w, r, "/nota/"+strconv.FormatInt(id, 10), http.StatusFound)
+		return
+	}
 	http.Redirect(w, r, "/notas", http.StatusFound)
 }
+
+// renderConflict muestra la pantalla de conflicto con la version guardada y la
+// version enviada por el usuario, para que pueda combinarlas antes de guardar.
+func renderConflict(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries, note db.Note, nombre, contenido string, tagID int64) {
+	current, err := getNoteForRequest(r, queries, note.ID)
+	if err != nil {
+		http.Error(w, "Error al obtener la nota", http.StatusInternalServerError)
+		return
+	}
+
+	allTags, err := queries.ListTags(r.Context(), note.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error al obtener los tags", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Note":        current,
+		"MiNombre":    nombre,
+		"MiContenido": contenido,
+		"TagID":       tagID,
+		"Tags":        allTags,
+		"Diff": RevisionDiff{
+			NombreAntes:   current.Nombre,
+			NombreDespues: nombre,
+			Lines:         diff.Lines(current.Contenido, contenido),
+		},
+	}
+
+	flash.Push(r, flash.Warning, "La nota fue modificada mientras la editabas")
+	Render(tpl, w, r, "conflicto_nota.html", data)
+}
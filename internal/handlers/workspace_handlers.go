@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
+	"github.com/Calevin/go_htmx_crud/internal/middleware"
+	"github.com/go-chi/chi/v5"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Roles de los miembros de un espacio de trabajo (columna workspace_members.role).
+const (
+	// workspaceRoleAdmin puede hacer todo con las notas del espacio y gestionar sus miembros.
+	workspaceRoleAdmin = "admin"
+	// workspaceRoleMember puede crear notas y editar las de los demas; borrar,
+	// archivar y compartir solo las suyas.
+	workspaceRoleMember = "miembro"
+	// workspaceRoleReader solo puede ver las notas del espacio.
+	workspaceRoleReader = "lector"
+)
+
+// workspaceRoles son los roles que se pueden elegir al invitar o cambiar un miembro.
+var workspaceRoles = []struct {
+	Value string
+	Label string
+}{
+	{workspaceRoleAdmin, "Administrador"},
+	{workspaceRoleMember, "Miembro"},
+	{workspaceRoleReader, "Lector"},
+}
+
+// invitationTTL es cuanto dura un enlace de invitacion.
+const invitationTTL = 7 * 24 * time.Hour
+
+// noteScope es el espacio donde se listan y se crean notas y tags: el espacio
+// personal de un usuario o un espacio de trabajo compartido con otros.
+type noteScope struct {
+	UserID int64
+	// WorkspaceID es el espacio de trabajo; NULL es el espacio personal.
+	WorkspaceID sql.NullInt64
+	// Role es el rol del usuario en el espacio de trabajo; vacio en el personal.
+	Role string
+}
+
+// personalScope es el espacio personal del usuario.
+func personalScope(userID int64) noteScope {
+	return noteScope{UserID: userID}
+}
+
+// scopeOf es el espacio al que pertenece la nota, visto por su dueño.
+func scopeOf(note db.Note) noteScope {
+	return noteScope{UserID: note.UserID.Int64, WorkspaceID: note.WorkspaceID}
+}
+
+// CanWrite indica si el usuario puede crear notas en el espacio.
+func (s noteScope) CanWrite() bool {
+	return s.Role != workspaceRoleReader
+}
+
+// Contains indica si la nota pertenece al espacio.
+func (s noteScope) Contains(note db.Note) bool {
+	if s.WorkspaceID.Valid {
+		return note.WorkspaceID == s.WorkspaceID
+	}
+	return !note.WorkspaceID.Valid && note.UserID == ownedBy(s.UserID)
+}
+
+// ContainsTag indica si la tag es del espacio.
+func (s noteScope) ContainsTag(tag db.Tag) bool {
+	return tag.WorkspaceID == s.WorkspaceID
+}
+
+// currentScope devuelve el espacio elegido por el usuario autenticado, que
+// el middleware LoadWorkspaces deja en el contexto.
+func currentScope(r *http.Request, queries *db.Queries) (noteScope, error) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		return noteScope{}, err
+	}
+
+	scope := personalScope(user.ID)
+	if workspaces, ok := r.Context().Value(middleware.WorkspaceContextKey).(*middleware.Workspaces); ok && workspaces.Current != nil {
+		scope.WorkspaceID = sql.NullInt64{Int64: workspaces.Current.ID, Valid: true}
+		scope.Role = workspaces.Current.Role
+	}
+	return scope, nil
+}
+
+// currentWorkspaces devuelve los espacios de trabajo del usuario para el
+// selector del layout, o nil en las paginas sin sesion.
+func currentWorkspaces(r *http.Request) *middleware.Workspaces {
+	workspaces, _ := r.Context().Value(middleware.WorkspaceContextKey).(*middleware.Workspaces)
+	return workspaces
+}
+
+// workspaceAccess es el acceso que da el rol en el espacio a una nota del espacio.
+func workspaceAccess(ctx context.Context, queries *db.Queries, note db.Note, userID int64) (noteAccess, error) {
+	member, err := queries.GetWorkspaceMember(ctx, db.GetWorkspaceMemberParams{
+		WorkspaceID: note.WorkspaceID.Int64,
+		UserID:      userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return accessNone, nil
+	}
+	if err != nil {
+		return accessNone, err
+	}
+
+	switch member.Role {
+	case workspaceRoleAdmin:
+		return accessOwner, nil
+	case workspaceRoleMember:
+		if note.UserID == ownedBy(userID) {
+			return accessOwner, nil
+		}
+		return accessEdit, nil
+	default:
+		return accessRead, nil
+	}
+}
+
+// setWorkspaceAccess marca una nota del listado de un espacio de trabajo con
+// lo que el rol del usuario le permite hacer, igual que setSharedAccess con
+// las notas compartidas. usernames guarda los autores ya buscados.
+func setWorkspaceAccess(ctx context.Context, queries *db.Queries, note *NoteWithTags, scope noteScope, authorID sql.NullInt64, usernames map[int64]string) error {
+	if !scope.WorkspaceID.Valid {
+		return nil
+	}
+	note.EnEspacio = true
+
+	mine := authorID == ownedBy(scope.UserID)
+	switch {
+	case scope.Role == workspaceRoleReader:
+		note.Compartida = sharePermissionRead
+	case scope.Role == workspaceRoleMember && !mine:
+		note.Compartida = sharePermissionEdit
+	}
+	if mine || !authorID.Valid {
+		return nil
+	}
+
+	username, ok := usernames[authorID.Int64]
+	if !ok {
+		author, err := queries.GetUser(ctx, authorID.Int64)
+		if err != nil {
+			return err
+		}
+		username = author.Username
+		usernames[authorID.Int64] = username
+	}
+	note.Duenio = username
+	return nil
+}
+
+// workspaceParam obtiene el espacio {id} de la URL y verifica que el usuario sea
+// miembro. Si no lo es escribe un 404, para no revelar que existe, y devuelve false.
+func workspaceParam(w http.ResponseWriter, r *http.Request, queries *db.Queries) (db.Workspace, db.WorkspaceMember, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return db.Workspace{}, db.WorkspaceMember{}, false
+	}
+	return authorizeWorkspace(w, r, queries, id)
+}
+
+// authorizeWorkspace obtiene el espacio id y la membresia del usuario autenticado.
+func authorizeWorkspace(w http.ResponseWriter, r *http.Request, queries *db.Queries, id int64) (db.Workspace, db.WorkspaceMember, bool) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return db.Workspace{}, db.WorkspaceMember{}, false
+	}
+
+	member, err := queries.GetWorkspaceMember(r.Context(), db.GetWorkspaceMemberParams{WorkspaceID: id, UserID: user.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "El espacio de trabajo no existe", http.StatusNotFound)
+		return db.Workspace{}, db.WorkspaceMember{}, false
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener el espacio de trabajo", http.StatusInternalServerError)
+		return db.Workspace{}, db.WorkspaceMember{}, false
+	}
+
+	workspace, err := queries.GetWorkspace(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Error al obtener el espacio de trabajo", http.StatusInternalServerError)
+		return db.Workspace{}, db.WorkspaceMember{}, false
+	}
+	return workspace, member, true
+}
+
+// validWorkspaceRole indica si role es uno de los roles de los espacios de trabajo.
+func validWorkspaceRole(role string) bool {
+	for _, r := range workspaceRoles {
+		if r.Value == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspacesHandler muestra los espacios de trabajo del usuario y el formulario
+// para crear uno nuevo.
+func WorkspacesHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	workspaces, err := queries.ListWorkspacesForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Error al obtener los espacios de trabajo", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Workspaces": workspaces,
+		"Roles":      workspaceRoles,
+	}
+
+	Render(tpl, w, r, "espacios.html", data)
+}
+
+// CreateWorkspaceHandler crea un espacio de trabajo con el usuario como
+// administrador y una copia de las tags generales, y lo elige como espacio actual.
+func CreateWorkspaceHandler(w http.ResponseWriter, r *http.Request, conn *sql.DB, queries *db.Queries) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	nombre := strings.TrimSpace(r.FormValue("nombre"))
+	if nombre == "" {
+		flash.Push(r, flash.Warning, "El espacio de trabajo necesita un nombre")
+		http.Redirect(w, r, "/espacios", http.StatusSeeOther)
+		return
+	}
+
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Error al crear el espacio de trabajo", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	workspace, err := qtx.CreateWorkspace(r.Context(), nombre)
+	if err == nil {
+		err = qtx.AddWorkspaceMember(r.Context(), db.AddWorkspaceMemberParams{
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			Role:        workspaceRoleAdmin,
+		})
+	}
+	if err == nil {
+		err = qtx.CopyGeneralTags(r.Context(), sql.NullInt64{Int64: workspace.ID, Valid: true})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		http.Error(w, "Error al crear el espacio de trabajo", http.StatusInternalServerError)
+		return
+	}
+
+	middleware.SetWorkspaceCookie(w, workspace.ID)
+	flash.Push(r, flash.Success, "Espacio de trabajo \""+workspace.Nombre+"\" creado")
+	http.Redirect(w, r, "/espacios/"+strconv.FormatInt(workspace.ID, 10), http.StatusSeeOther)
+}
+
+// SwitchWorkspaceHandler cambia el espacio de trabajo elegido. Un valor vacio
+// vuelve al espacio personal.
+func SwitchWorkspaceHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	value := r.FormValue("espacio")
+	if value == "" {
+		middleware.SetWorkspaceCookie(w, 0)
+		http.Redirect(w, r, "/notas", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+	if _, _, ok := authorizeWorkspace(w, r, queries, id); !ok {
+		return
+	}
+
+	middleware.SetWorkspaceCookie(w, id)
+	http.Redirect(w, r, "/notas", http.StatusSeeOther)
+}
+
+// WorkspaceHandler muestra los miembros de un espacio de trabajo y, a sus
+// administradores, las invitaciones y los controles para gestionar los miembros.
+func WorkspaceHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	workspace, member, ok := workspaceParam(w, r, queries)
+	if !ok {
+		return
+	}
+
+	members, err := queries.ListWorkspaceMembers(r.Context(), workspace.ID)
+	if err != nil {
+		http.Error(w, "Error al obtener los miembros", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Workspace": workspace,
+		"Member":    member,
+		"Members":   members,
+		"Roles":     workspaceRoles,
+		"EsAdmin":   member.Role == workspaceRoleAdmin,
+	}
+
+	if member.Role == workspaceRoleAdmin {
+		list, err := queries.ListWorkspaceInvitations(r.Context(), workspace.ID)
+		if err != nil {
+			http.Error(w, "Error al obtener las invitaciones", http.StatusInternalServerError)
+			return
+		}
+
+		invitations := make([]Invitation, 0, len(list))
+		for _, invitation := range list {
+			invitations = append(invitations, Invitation{
+				WorkspaceInvitation: invitation,
+				URL:                 requestScheme(r) + "://" + r.Host + "/invitaciones/" + invitation.Token,
+			})
+		}
+		data["Invitations"] = invitations
+	}
+
+	Render(tpl, w, r, "espacio.html", data)
+}
+
+// Invitation es un enlace de invitacion a un espacio junto con su URL completa.
+type Invitation struct {
+	db.WorkspaceInvitation
+	URL string
+}
+
+// Expired indica si la invitacion ya expiro.
+func (i Invitation) Expired() bool {
+	return i.ExpiresAt.Before(time.Now())
+}
+
+// CreateInvitationHandler crea un enlace de invitacion al espacio con el rol
+// elegido. Lo puede usar cualquier usuario con sesion hasta que expire o se revoque.
+func CreateInvitationHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	workspace, member, ok := workspaceParam(w, r, queries)
+	if !ok {
+		return
+	}
+	if member.Role != workspaceRoleAdmin {
+		http.Error(w, "Solo los administradores pueden invitar", http.StatusForbidden)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+
+	role := r.FormValue("rol")
+	if !validWorkspaceRole(role) {
+		http.Error(w, "Rol inválido", http.StatusBadRequest)
+		return
+	}
+
+	// El token es aleatorio, igual que el de los enlaces publicos
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		http.Error(w, "Error al generar la invitación", http.StatusInternalServerError)
+		return
+	}
+
+	_, err := queries.CreateWorkspaceInvitation(r.Context(), db.CreateWorkspaceInvitationParams{
+		WorkspaceID: workspace.ID,
+		Token:       hex.EncodeToString(buf),
+		Role:        role,
+		CreatedBy:   ownedBy(member.UserID),
+		ExpiresAt:   time.Now().Add(invitationTTL).UTC(),
+	})
+	if err != nil {
+		http.Error(w, "Error al crear la invitación", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Invitación creada")
+	http.Redirect(w, r, "/espacios/"+strconv.FormatInt(workspace.ID, 10), http.StatusSeeOther)
+}
+
+// RevokeInvitationHandler elimina un enlace de invitacion; deja de funcionar en el momento.
+func RevokeInvitationHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := queries.GetWorkspaceInvitation(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "La invitación no existe", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener la invitación", http.StatusInternalServerError)
+		return
+	}
+
+	_, member, ok := authorizeWorkspace(w, r, queries, invitation.WorkspaceID)
+	if !ok {
+		return
+	}
+	if member.Role != workspaceRoleAdmin {
+		http.Error(w, "Solo los administradores pueden revocar invitaciones", http.StatusForbidden)
+		return
+	}
+
+	if err := queries.DeleteWorkspaceInvitation(r.Context(), id); err != nil {
+		http.Error(w, "Error al revocar la invitación", http.StatusInternalServerError)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Invitación revocada")
+	w.WriteHeader(http.StatusOK)
+}
+
+// InvitationHandler muestra a quien abre un enlace de invitacion a que espacio
+// lo invitan (GET) y lo agrega como miembro cuando acepta (POST). Si ya era
+// miembro conserva su rol.
+func InvitationHandler(w http.ResponseWriter, r *http.Request, tpl *template.Template, queries *db.Queries) {
+	invitation, err := queries.GetWorkspaceInvitationByToken(r.Context(), chi.URLParam(r, "token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		Render(tpl, w, r, "invitacion.html", map[string]any{
+			"Error": "Esta invitación no existe o fue revocada.",
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error al obtener la invitación", http.StatusInternalServerError)
+		return
+	}
+	if (Invitation{WorkspaceInvitation: invitation}).Expired() {
+		Render(tpl, w, r, "invitacion.html", map[string]any{
+			"Error": "Esta invitación expiró. Pide una nueva a un administrador del espacio.",
+		})
+		return
+	}
+
+	workspace, err := queries.GetWorkspace(r.Context(), invitation.WorkspaceID)
+	if err != nil {
+		http.Error(w, "Error al obtener el espacio de trabajo", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := currentUser(r, queries)
+	if err != nil {
+		http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		Render(tpl, w, r, "invitacion.html", map[string]any{
+			"Invitation": invitation,
+			"Workspace":  workspace,
+			"Roles":      workspaceRoles,
+		})
+		return
+	}
+
+	err = queries.AddWorkspaceMember(r.Context(), db.AddWorkspaceMemberParams{
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+		Role:        invitation.Role,
+	})
+	if err == nil {
+		err = queries.CountWorkspaceInvitationUse(r.Context(), invitation.ID)
+	}
+	if err != nil {
+		http.Error(w, "Error al aceptar la invitación", http.StatusInternalServerError)
+		return
+	}
+
+	middleware.SetWorkspaceCookie(w, workspace.ID)
+	flash.Push(r, flash.Success, "Ahora eres parte de \""+workspace.Nombre+"\"")
+	http.Redirect(w, r, "/notas", http.StatusSeeOther)
+}
+
+// memberParam obtiene el miembro {usuario} de la URL dentro del espacio.
+func memberParam(w http.ResponseWriter, r *http.Request, queries *db.Queries, workspaceID int64) (db.User, bool) {
+	user, err := queries.GetUserByUsername(r.Context(), chi.URLParam(r, "usuario"))
+	if err == nil {
+		_, err = queries.GetWorkspaceMember(r.Context(), db.GetWorkspaceMemberParams{WorkspaceID: workspaceID, UserID: user.ID})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "El usuario no es miembro del espacio", http.StatusNotFound)
+		return db.User{}, false
+	}
+	if err != nil {
+		http.Error(w, "Error al buscar el miembro", http.StatusInternalServerError)
+		return db.User{}, false
+	}
+	return user, true
+}
+
+// SetMemberRoleHandler cambia el rol de un miembro del espacio. Solo lo pueden
+// hacer los administradores.
+func SetMemberRoleHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	workspace, member, ok := workspaceParam(w, r, queries)
+	if !ok {
+		return
+	}
+	if member.Role != workspaceRoleAdmin {
+		http.Error(w, "Solo los administradores pueden cambiar roles", http.StatusForbidden)
+		return
+	}
+
+	user, ok := memberParam(w, r, queries, workspace.ID)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error al parsear el formulario", http.StatusBadRequest)
+		return
+	}
+	role := r.FormValue("rol")
+	if !validWorkspaceRole(role) {
+		http.Error(w, "Rol inválido", http.StatusBadRequest)
+		return
+	}
+
+	back := "/espacios/" + strconv.FormatInt(workspace.ID, 10)
+	updated, err := queries.SetWorkspaceMemberRole(r.Context(), db.SetWorkspaceMemberRoleParams{
+		Role:        role,
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		http.Error(w, "Error al cambiar el rol", http.StatusInternalServerError)
+		return
+	}
+	// El miembro existe, asi que si no se actualizo es el ultimo administrador
+	if updated == 0 {
+		flash.Push(r, flash.Warning, "El espacio necesita al menos un administrador")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	flash.Push(r, flash.Success, "Rol de "+user.Username+" actualizado")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// RemoveMemberHandler quita a un miembro del espacio. Los administradores
+// pueden quitar a cualquiera y cualquier miembro puede irse del espacio. Las
+// notas que creo quedan en el espacio.
+func RemoveMemberHandler(w http.ResponseWriter, r *http.Request, queries *db.Queries) {
+	workspace, member, ok := workspaceParam(w, r, queries)
+	if !ok {
+		return
+	}
+
+	user, ok := memberParam(w, r, queries, workspace.ID)
+	if !ok {
+		return
+	}
+
+	leaving := user.ID == member.UserID
+	if !leaving && member.Role != workspaceRoleAdmin {
+		http.Error(w, "Solo los administradores pueden quitar miembros", http.StatusForbidden)
+		return
+	}
+
+	removed, err := queries.RemoveWorkspaceMember(r.Context(), db.RemoveWorkspaceMemberParams{
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		http.Error(w, "Error al quitar el miembro", http.StatusInternalServerError)
+		return
+	}
+	if removed == 0 {
+		http.Error(w, "El espacio necesita al menos un administrador", http.StatusConflict)
+		return
+	}
+
+	if leaving {
+		middleware.SetWorkspaceCookie(w, 0)
+		flash.Push(r, flash.Success, "Ya no eres parte de \""+workspace.Nombre+"\"")
+		w.Header().Set("HX-Redirect", "/espacios")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	flash.Push(r, flash.Success, user.Username+" ya no es parte del espacio")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,134 @@
+// Package ical genera calendarios en formato iCalendar (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind es el tipo de componente con el que se publica cada entrada.
+type Kind int
+
+const (
+	// Event publica las entradas como VEVENT, que muestran todas las aplicaciones de calendario.
+	Event Kind = iota
+	// Todo publica las entradas como VTODO (tareas con fecha de vencimiento).
+	Todo
+)
+
+// Entry es una entrada del calendario.
+type Entry struct {
+	// UID identifica la entrada y no debe cambiar entre versiones del calendario.
+	UID         string
+	Summary     string
+	Description string
+	Due         time.Time
+	Modified    time.Time
+	// Sequence aumenta cada vez que la entrada cambia.
+	Sequence int64
+}
+
+// maxLineLength es el largo maximo de una linea en octetos, sin contar el CRLF.
+const maxLineLength = 75
+
+// Write escribe un calendario con las entradas dadas.
+func Write(w io.Writer, name string, kind Kind, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//go_htmx_crud//Notas//ES")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", Escape(name))
+
+	component := "VEVENT"
+	if kind == Todo {
+		component = "VTODO"
+	}
+
+	for _, e := range entries {
+		line("BEGIN", component)
+		line("UID", Escape(e.UID))
+		line("DTSTAMP", formatTime(e.Modified))
+		line("LAST-MODIFIED", formatTime(e.Modified))
+		line("SEQUENCE", strconv.FormatInt(e.Sequence, 10))
+		if kind == Todo {
+			line("DUE", formatTime(e.Due))
+		} else {
+			line("DTSTART", formatTime(e.Due))
+			line("DTEND", formatTime(e.Due))
+		}
+		line("SUMMARY", Escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", Escape(e.Description))
+		}
+		line("END", component)
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// Escape escapa un valor de tipo TEXT: barras invertidas, punto y coma, comas
+// y saltos de linea.
+func Escape(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b.WriteString(`\\`)
+		case ';':
+			b.WriteString(`\;`)
+		case ',':
+			b.WriteString(`\,`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			// Los CRLF se escriben como un unico salto de linea
+			if i+1 < len(s) && s[i+1] == '\n' {
+				continue
+			}
+			b.WriteString(`\n`)
+		default:
+			// Los demas caracteres de control no estan permitidos en TEXT
+			if c < 0x20 && c != '\t' || c == 0x7f {
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// writeLine escribe una linea de contenido, plegandola cada 75 octetos sin
+// cortar caracteres UTF-8. Las lineas de continuacion empiezan con un espacio.
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// El espacio inicial cuenta dentro del largo de la linea
+		limit = maxLineLength - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// formatTime formatea t como fecha y hora UTC.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
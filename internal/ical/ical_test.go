@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"sin cambios", "Reunión de equipo", "Reunión de equipo"},
+		{"coma y punto y coma", "a,b;c", `a\,b\;c`},
+		{"barra invertida", `C:\notas`, `C:\\notas`},
+		{"salto de linea", "uno\ndos", `uno\ndos`},
+		{"CRLF", "uno\r\ndos", `uno\ndos`},
+		{"CR solo", "uno\rdos", `uno\ndos`},
+		{"caracteres de control", "a\x00b\x1bc\x7fd", "abcd"},
+		{"tabulacion", "a\tb", "a\tb"},
+		{"dos puntos sin escapar", "hora: 10:30", "hora: 10:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Escape(tt.in); got != tt.want {
+				t.Errorf("Escape(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"corta", "SUMMARY:hola"},
+		{"justo 75 octetos", "SUMMARY:" + strings.Repeat("a", 75-len("SUMMARY:"))},
+		{"76 octetos", "SUMMARY:" + strings.Repeat("a", 76-len("SUMMARY:"))},
+		{"ASCII largo", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"acentos de 2 octetos", "SUMMARY:" + strings.Repeat("ñá", 80)},
+		{"ideogramas de 3 octetos", "SUMMARY:" + strings.Repeat("日本語", 40)},
+		{"emoji de 4 octetos", "SUMMARY:" + strings.Repeat("🗓️📌", 30)},
+		{"multibyte en el limite", "SUMMARY:" + strings.Repeat("a", 74-len("SUMMARY:")) + "é" + strings.Repeat("b", 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			bw := bufio.NewWriter(&buf)
+			writeLine(bw, tt.in)
+			bw.Flush()
+			out := buf.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("la linea no termina en CRLF: %q", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, l := range lines {
+				if len(l) > maxLineLength {
+					t.Errorf("linea %d de %d octetos: %q", i, len(l), l)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("linea %d corta un caracter UTF-8: %q", i, l)
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("la linea de continuacion %d no empieza con espacio: %q", i, l)
+				}
+			}
+
+			// Desplegar (RFC 5545, 3.1) devuelve la linea original
+			if got := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); got != tt.in {
+				t.Errorf("al desplegar se obtuvo %q, se esperaba %q", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	due := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	modified := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	entries := []Entry{{
+		UID:         "nota-1@go_htmx_crud",
+		Summary:     "Entregar, informe; final",
+		Description: "Primera línea\nSegunda línea",
+		Due:         due,
+		Modified:    modified,
+		Sequence:    2,
+	}}
+
+	tests := []struct {
+		kind Kind
+		want []string
+	}{
+		{Event, []string{"BEGIN:VEVENT", "DTSTART:20260310T150000Z", "DTEND:20260310T150000Z", "END:VEVENT"}},
+		{Todo, []string{"BEGIN:VTODO", "DUE:20260310T150000Z", "END:VTODO"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, "Mis notas", tt.kind, entries); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		out := buf.String()
+
+		want := append([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"X-WR-CALNAME:Mis notas",
+			"UID:nota-1@go_htmx_crud",
+			"DTSTAMP:20260301T093000Z",
+			"LAST-MODIFIED:20260301T093000Z",
+			"SEQUENCE:2",
+			`SUMMARY:Entregar\, informe\; final`,
+			`DESCRIPTION:Primera línea\nSegunda línea`,
+			"END:VCALENDAR",
+		}, tt.want...)
+		for _, line := range want {
+			if !strings.Contains(out, line+"\r\n") {
+				t.Errorf("falta la linea %q en:\n%s", line, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package markdown
+
+import (
+	"bytes"
+	"container/list"
+	"html/template"
+	"log"
+	"regexp"
+	"sync"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+)
+
+// HighlightStyle es el estilo de chroma usado para generar static/css/chroma.css.
+const HighlightStyle = "github"
+
+// cacheSize es la cantidad maxima de notas renderizadas que se guardan en memoria.
+const cacheSize = 500
+
+// Renderer convierte Markdown (CommonMark + GFM) en HTML sanitizado.
+// Es seguro para uso concurrente.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+
+	mu    sync.Mutex
+	cache map[cacheKey]*list.Element
+	lru   *list.List
+}
+
+// cacheKey identifica una revision de una nota. La version cambia en cada edicion,
+// por lo que el HTML cacheado nunca queda desactualizado.
+type cacheKey struct {
+	noteID  int64
+	version int64
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	html  template.HTML
+	tasks TaskProgress
+}
+
+// New crea un Renderer con las extensiones de GFM (tablas, listas de tareas,
+// tachado y autolinks), enlaces entre notas ([[Nombre]]) y resaltado de
+// sintaxis en los bloques de codigo.
+func New() *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			wikiLinks{},
+			highlighting.NewHighlighting(
+				highlighting.WithStyle(HighlightStyle),
+				// Se usan clases CSS porque el sanitizador elimina los estilos inline
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+	)
+
+	return &Renderer{
+		md:     md,
+		policy: newPolicy(),
+		cache:  make(map[cacheKey]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+// newPolicy parte de la politica para contenido de usuarios de bluemonday y
+// permite lo necesario para el resaltado de sintaxis y las listas de tareas.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)).OnElements("pre", "code", "span", "div", "a")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render convierte src en HTML sanitizado.
+func (r *Renderer) Render(src string) template.HTML {
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(src), &buf); err != nil {
+		// Ante un error se muestra el texto original escapado
+		log.Printf("Error renderizando markdown: %v", err)
+		return template.HTML(template.HTMLEscapeString(src))
+	}
+	return template.HTML(r.policy.SanitizeBytes(buf.Bytes()))
+}
+
+// RenderNote renderiza el contenido de una nota, usando la cache por revision.
+// A diferencia de Render, las casillas de las tareas quedan habilitadas y con
+// su indice en data-tarea para poder marcarlas desde la tarjeta.
+func (r *Renderer) RenderNote(noteID, version int64, src string) template.HTML {
+	return r.note(noteID, version, src).html
+}
+
+// NoteTasks devuelve el progreso de las tareas de una nota, usando la cache por revision.
+func (r *Renderer) NoteTasks(noteID, version int64, src string) TaskProgress {
+	return r.note(noteID, version, src).tasks
+}
+
+func (r *Renderer) note(noteID, version int64, src string) *cacheEntry {
+	key := cacheKey{noteID: noteID, version: version}
+
+	r.mu.Lock()
+	if el, ok := r.cache[key]; ok {
+		r.lru.MoveToFront(el)
+		entry := el.Value.(*cacheEntry)
+		r.mu.Unlock()
+		return entry
+	}
+	r.mu.Unlock()
+
+	tasks := r.tasks([]byte(src))
+	entry := &cacheEntry{
+		key:   key,
+		html:  template.HTML(interactiveCheckboxes(string(r.Render(src)), tasks)),
+		tasks: progress(tasks),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cache[key]; !ok {
+		r.cache[key] = r.lru.PushFront(entry)
+		if r.lru.Len() > cacheSize {
+			oldest := r.lru.Back()
+			r.lru.Remove(oldest)
+			delete(r.cache, oldest.Value.(*cacheEntry).key)
+		}
+	}
+	return entry
+}
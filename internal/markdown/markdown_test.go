@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []string
+		notWant []string
+	}{
+		{"enfasis", "**negrita** y _cursiva_", []string{"<strong>negrita</strong>", "<em>cursiva</em>"}, nil},
+		{"tabla", "| a | b |\n|---|---|\n| 1 | 2 |\n", []string{"<table>", "<td>1</td>"}, nil},
+		{"tachado", "~~viejo~~", []string{"<del>viejo</del>"}, nil},
+		{"autolink", "ver https://example.com", []string{`<a href="https://example.com"`}, nil},
+		{"tareas", "- [x] hecha\n", []string{`type="checkbox"`, "checked"}, nil},
+		{"codigo resaltado", "```go\nfunc main() {}\n```\n", []string{`<pre class="chroma">`}, []string{"style="}},
+		{"HTML crudo", "<script>alert(1)</script>\n\ntexto", []string{"texto"}, []string{"<script", "alert(1)"}},
+		{"HTML en linea", `hola <img src=x onerror="alert(1)">`, []string{"hola"}, []string{"onerror", "<img"}},
+		{"enlace javascript", "[clic](javascript:alert(1))", []string{"clic"}, []string{"javascript:"}},
+	}
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := string(r.Render(tt.src))
+			for _, want := range tt.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("falta %q en %s", want, html)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(html, notWant) {
+					t.Errorf("no deberia estar %q en %s", notWant, html)
+				}
+			}
+		})
+	}
+}
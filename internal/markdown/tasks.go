@@ -0,0 +1,112 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	gast "github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ErrTaskNotFound se devuelve cuando el indice de la tarea no existe en el contenido.
+var ErrTaskNotFound = errors.New("markdown: tarea no encontrada")
+
+// TaskProgress resume las tareas (- [ ] / - [x]) de una nota.
+type TaskProgress struct {
+	Done  int
+	Total int
+}
+
+// Percent devuelve el porcentaje de tareas completadas.
+func (p TaskProgress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Done * 100 / p.Total
+}
+
+// task es una tarea encontrada en el Markdown. offset es la posicion del "["
+// de la casilla en el texto original.
+type task struct {
+	offset  int
+	checked bool
+}
+
+// tasks devuelve las tareas de src en el mismo orden en que se renderizan.
+func (r *Renderer) tasks(src []byte) []task {
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var found []task
+	_ = gast.Walk(doc, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		cb, ok := n.(*extast.TaskCheckBox)
+		if !ok || !entering {
+			return gast.WalkContinue, nil
+		}
+		// La casilla siempre esta al comienzo de la primera linea del bloque de texto del item
+		lines := cb.Parent().Lines()
+		if lines.Len() == 0 {
+			return gast.WalkContinue, nil
+		}
+		offset := lines.At(0).Start
+		if offset+2 < len(src) && src[offset] == '[' {
+			found = append(found, task{offset: offset, checked: cb.IsChecked})
+		}
+		return gast.WalkContinue, nil
+	})
+	return found
+}
+
+// progress cuenta las tareas completadas.
+func progress(tasks []task) TaskProgress {
+	p := TaskProgress{Total: len(tasks)}
+	for _, t := range tasks {
+		if t.checked {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// ToggleTask marca o desmarca la tarea numero index (empezando en 0) y devuelve
+// el contenido modificado. Solo se reescribe la casilla de esa linea.
+func (r *Renderer) ToggleTask(src string, index int) (string, error) {
+	b := []byte(src)
+	found := r.tasks(b)
+	if index < 0 || index >= len(found) {
+		return "", fmt.Errorf("%w: %d", ErrTaskNotFound, index)
+	}
+
+	t := found[index]
+	if t.checked {
+		b[t.offset+1] = ' '
+	} else {
+		b[t.offset+1] = 'x'
+	}
+	return string(b), nil
+}
+
+// checkboxRegexp encuentra las casillas renderizadas por la extension de tareas.
+// Como el HTML crudo del usuario se omite, todas las casillas del resultado son tareas.
+var checkboxRegexp = regexp.MustCompile(`<input[^>]*type="checkbox"[^>]*>`)
+
+// interactiveCheckboxes habilita las casillas del HTML sanitizado y les agrega el
+// indice de la tarea en data-tarea, para poder marcarlas desde la tarjeta.
+func interactiveCheckboxes(html string, tasks []task) string {
+	// Si no coinciden las cantidades se dejan las casillas deshabilitadas
+	if len(checkboxRegexp.FindAllStringIndex(html, -1)) != len(tasks) {
+		return html
+	}
+
+	i := 0
+	return checkboxRegexp.ReplaceAllStringFunc(html, func(string) string {
+		checked := ""
+		if tasks[i].checked {
+			checked = " checked"
+		}
+		input := fmt.Sprintf(`<input type="checkbox" class="tarea" data-tarea="%d"%s>`, i, checked)
+		i++
+		return input
+	})
+}
@@ -0,0 +1,110 @@
+package markdown
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToggleTask(t *testing.T) {
+	r := New()
+	tests := []struct {
+		name  string
+		src   string
+		index int
+		want  string
+	}{
+		{"marcar", "- [ ] uno\n- [ ] dos\n", 1, "- [ ] uno\n- [x] dos\n"},
+		{"desmarcar", "- [x] uno\n- [ ] dos\n", 0, "- [ ] uno\n- [ ] dos\n"},
+		{"X mayuscula", "- [X] uno\n", 0, "- [ ] uno\n"},
+		{"lista numerada", "1. [ ] uno\n", 0, "1. [x] uno\n"},
+		{"anidada", "- [ ] uno\n  - [ ] dos\n", 1, "- [ ] uno\n  - [x] dos\n"},
+		{
+			"ignora casillas en codigo",
+			"```\n- [ ] no es tarea\n```\n- [ ] uno\n",
+			0,
+			"```\n- [ ] no es tarea\n```\n- [x] uno\n",
+		},
+		{"texto con corchetes", "- [ ] ver [1]\n", 0, "- [x] ver [1]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ToggleTask(tt.src, tt.index)
+			if err != nil {
+				t.Fatalf("ToggleTask: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ToggleTask(%q, %d) = %q, se esperaba %q", tt.src, tt.index, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToggleTaskNotFound(t *testing.T) {
+	r := New()
+	for _, index := range []int{-1, 1} {
+		if _, err := r.ToggleTask("- [ ] uno\n", index); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("ToggleTask con indice %d: error = %v, se esperaba %v", index, err, ErrTaskNotFound)
+		}
+	}
+	if _, err := r.ToggleTask("texto sin tareas\n", 0); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("texto sin tareas: error = %v, se esperaba %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestInteractiveCheckboxes(t *testing.T) {
+	tasks := []task{{checked: false}, {checked: true}}
+	tests := []struct {
+		name  string
+		html  string
+		tasks []task
+		want  string
+	}{
+		{
+			"habilita y numera",
+			`<li><input disabled="" type="checkbox"> uno</li><li><input checked="" disabled="" type="checkbox"> dos</li>`,
+			tasks,
+			`<li><input type="checkbox" class="tarea" data-tarea="0"> uno</li><li><input type="checkbox" class="tarea" data-tarea="1" checked> dos</li>`,
+		},
+		{
+			"cantidades distintas",
+			`<li><input disabled="" type="checkbox"> uno</li>`,
+			tasks,
+			`<li><input disabled="" type="checkbox"> uno</li>`,
+		},
+		{"sin tareas", "<p>texto</p>", nil, "<p>texto</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interactiveCheckboxes(tt.html, tt.tasks); got != tt.want {
+				t.Errorf("interactiveCheckboxes = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteTasks(t *testing.T) {
+	r := New()
+	got := r.NoteTasks(1, 1, "- [x] uno\n- [ ] dos\n- [x] tres\n- cuatro\n")
+	if got != (TaskProgress{Done: 2, Total: 3}) {
+		t.Errorf("NoteTasks = %+v, se esperaba 2 de 3", got)
+	}
+	if got.Percent() != 66 {
+		t.Errorf("Percent = %d, se esperaba 66", got.Percent())
+	}
+	if (TaskProgress{}).Percent() != 0 {
+		t.Error("sin tareas el porcentaje deberia ser 0")
+	}
+}
+
+func TestRenderNoteCheckboxes(t *testing.T) {
+	html := string(New().RenderNote(1, 1, "- [ ] uno\n- [x] dos\n"))
+	for _, want := range []string{`data-tarea="0">`, `data-tarea="1" checked>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("falta %q en %s", want, html)
+		}
+	}
+	if strings.Contains(html, "disabled") {
+		t.Errorf("las casillas deberian estar habilitadas: %s", html)
+	}
+}
@@ -0,0 +1,225 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// WikiLink es un enlace a otra nota escrito como [[Nombre de la nota]] o
+// [[#id]], opcionalmente con un texto distinto: [[Nombre|texto]].
+type WikiLink struct {
+	// Name es el nombre de la nota enlazada; vacio si el enlace es por id.
+	Name string
+	// ID es el id de la nota enlazada; 0 si el enlace es por nombre.
+	ID int64
+	// Label es el texto que se muestra, vacio si no se indico.
+	Label string
+}
+
+// Href devuelve la URL del enlace. Los enlaces por nombre se resuelven al
+// hacer clic, asi el HTML no depende de las demas notas y se puede cachear.
+func (l WikiLink) Href() string {
+	if l.ID != 0 {
+		return fmt.Sprintf("/nota/%d", l.ID)
+	}
+	return "/ir_a_nota?nombre=" + url.QueryEscape(l.Name)
+}
+
+// Text devuelve el texto a mostrar del enlace.
+func (l WikiLink) Text() string {
+	switch {
+	case l.Label != "":
+		return l.Label
+	case l.ID != 0:
+		return fmt.Sprintf("#%d", l.ID)
+	}
+	return l.Name
+}
+
+// String devuelve el enlace escrito como [[...]].
+func (l WikiLink) String() string {
+	target := l.Name
+	if l.ID != 0 {
+		target = fmt.Sprintf("#%d", l.ID)
+	}
+	if l.Label != "" {
+		return "[[" + target + "|" + l.Label + "]]"
+	}
+	return "[[" + target + "]]"
+}
+
+// parseWikiLink interpreta el contenido entre [[ y ]].
+func parseWikiLink(inner string) (WikiLink, bool) {
+	target, label, _ := strings.Cut(inner, "|")
+	target = strings.TrimSpace(target)
+	link := WikiLink{Label: strings.TrimSpace(label)}
+	if target == "" {
+		return link, false
+	}
+
+	if rest, ok := strings.CutPrefix(target, "#"); ok {
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			return link, false
+		}
+		link.ID = id
+		return link, true
+	}
+	link.Name = target
+	return link, true
+}
+
+// kindWikiLink es el tipo de nodo del AST de los enlaces entre notas.
+var kindWikiLink = gast.NewNodeKind("WikiLink")
+
+// wikiLinkNode es un enlace entre notas en el AST. start y stop son las
+// posiciones del enlace completo ([[...]]) en el texto original.
+type wikiLinkNode struct {
+	gast.BaseInline
+	link        WikiLink
+	start, stop int
+}
+
+func (n *wikiLinkNode) Kind() gast.NodeKind {
+	return kindWikiLink
+}
+
+func (n *wikiLinkNode) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, map[string]string{"Link": n.link.Href()}, nil)
+}
+
+// wikiLinkParser reconoce [[...]] antes que el parser de enlaces de Markdown.
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wikiLinkParser) Parse(parent gast.Node, block text.Reader, pc parser.Context) gast.Node {
+	line, segment := block.PeekLine()
+	if len(line) < 5 || line[1] != '[' {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := line[2 : 2+end]
+	if bytes.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+
+	link, ok := parseWikiLink(string(inner))
+	if !ok {
+		return nil
+	}
+	length := end + 4
+	block.Advance(length)
+	return &wikiLinkNode{link: link, start: segment.Start, stop: segment.Start + length}
+}
+
+// wikiLinkRenderer escribe los enlaces entre notas como <a class="wikilink">.
+type wikiLinkRenderer struct{}
+
+func (wikiLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindWikiLink, func(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		link := node.(*wikiLinkNode).link
+		w.WriteString(`<a class="wikilink" href="`)
+		w.Write(util.EscapeHTML(util.URLEscape([]byte(link.Href()), false)))
+		w.WriteString(`">`)
+		w.Write(util.EscapeHTML([]byte(link.Text())))
+		w.WriteString(`</a>`)
+		return gast.WalkSkipChildren, nil
+	})
+}
+
+// wikiLinks es la extension de goldmark para los enlaces entre notas.
+type wikiLinks struct{}
+
+func (wikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 199)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(wikiLinkRenderer{}, 199)))
+}
+
+// wikiLinkNodes devuelve los enlaces entre notas de src, sin contar los que
+// estan dentro de bloques de codigo.
+func (r *Renderer) wikiLinkNodes(src []byte) []*wikiLinkNode {
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var found []*wikiLinkNode
+	_ = gast.Walk(doc, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if link, ok := n.(*wikiLinkNode); ok && entering {
+			found = append(found, link)
+		}
+		return gast.WalkContinue, nil
+	})
+	return found
+}
+
+// WikiLinks devuelve los enlaces a otras notas que hay en src.
+func (r *Renderer) WikiLinks(src string) []WikiLink {
+	nodes := r.wikiLinkNodes([]byte(src))
+	links := make([]WikiLink, 0, len(nodes))
+	for _, n := range nodes {
+		links = append(links, n.link)
+	}
+	return links
+}
+
+// RenameWikiLinks reemplaza los enlaces por nombre a oldName (sin distinguir
+// mayusculas) por enlaces a newName, conservando el texto de cada enlace.
+func (r *Renderer) RenameWikiLinks(src, oldName, newName string) string {
+	return r.rewriteWikiLinks(src, func(link WikiLink) (WikiLink, bool) {
+		if link.ID != 0 || !strings.EqualFold(link.Name, oldName) {
+			return link, false
+		}
+		link.Name = newName
+		return link, true
+	})
+}
+
+// RemapWikiLinkIDs reemplaza los enlaces por id ([[#id]]) segun ids, que
+// traduce los ids viejos a los nuevos. Los ids que no estan en ids no se tocan.
+func (r *Renderer) RemapWikiLinkIDs(src string, ids map[int64]int64) string {
+	return r.rewriteWikiLinks(src, func(link WikiLink) (WikiLink, bool) {
+		id, ok := ids[link.ID]
+		if link.ID == 0 || !ok || id == link.ID {
+			return link, false
+		}
+		link.ID = id
+		return link, true
+	})
+}
+
+// rewriteWikiLinks reescribe en src los enlaces para los que replace devuelve true.
+func (r *Renderer) rewriteWikiLinks(src string, replace func(WikiLink) (WikiLink, bool)) string {
+	b := []byte(src)
+	nodes := r.wikiLinkNodes(b)
+
+	var out bytes.Buffer
+	last := 0
+	for _, n := range nodes {
+		link, ok := replace(n.link)
+		if !ok {
+			continue
+		}
+		out.Write(b[last:n.start])
+		out.WriteString(link.String())
+		last = n.stop
+	}
+	out.Write(b[last:])
+	return out.String()
+}
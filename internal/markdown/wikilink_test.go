@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWikiLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []WikiLink
+	}{
+		{"por nombre", "ver [[Ideas]]", []WikiLink{{Name: "Ideas"}}},
+		{"por id", "ver [[#12]]", []WikiLink{{ID: 12}}},
+		{"con texto", "ver [[Ideas | mis ideas]]", []WikiLink{{Name: "Ideas", Label: "mis ideas"}}},
+		{"id con texto", "[[#3|otra]]", []WikiLink{{ID: 3, Label: "otra"}}},
+		{"varios", "[[a]] y [[b]]", []WikiLink{{Name: "a"}, {Name: "b"}}},
+		{"vacio", "[[ ]] y [[|texto]]", []WikiLink{}},
+		{"id invalido", "[[#0]] [[#x]]", []WikiLink{}},
+		{"en codigo", "`[[a]]`\n\n```\n[[b]]\n```\n", []WikiLink{}},
+	}
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.WikiLinks(tt.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WikiLinks(%q) = %#v, se esperaba %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWikiLinks(t *testing.T) {
+	html := string(New().Render("[[Mi nota]] y [[#7|la siete]]"))
+	for _, want := range []string{
+		`href="/ir_a_nota?nombre=Mi+nota"`,
+		">Mi nota</a>",
+		`href="/nota/7"`,
+		">la siete</a>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("falta %q en %s", want, html)
+		}
+	}
+}
+
+func TestRenameWikiLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"simple", "ver [[Viejo]].", "ver [[Nuevo]]."},
+		{"sin distinguir mayusculas", "[[viejo]] y [[VIEJO]]", "[[Nuevo]] y [[Nuevo]]"},
+		{"conserva el texto", "[[Viejo|aca]]", "[[Nuevo|aca]]"},
+		{"espacios internos", "[[ Viejo ]]", "[[Nuevo]]"},
+		{"otros enlaces", "[[Otro]] [[#4]] [[Viejo2]]", "[[Otro]] [[#4]] [[Viejo2]]"},
+		{"en codigo", "`[[Viejo]]` [[Viejo]]", "`[[Viejo]]` [[Nuevo]]"},
+		{"sin enlaces", "Viejo", "Viejo"},
+	}
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.RenameWikiLinks(tt.src, "Viejo", "Nuevo"); got != tt.want {
+				t.Errorf("RenameWikiLinks(%q) = %q, se esperaba %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemapWikiLinkIDs(t *testing.T) {
+	ids := map[int64]int64{1: 10, 2: 20, 3: 3}
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"simple", "[[#1]]", "[[#10]]"},
+		{"con texto", "[[#2|dos]] y [[#1]]", "[[#20|dos]] y [[#10]]"},
+		{"sin traduccion", "[[#5]]", "[[#5]]"},
+		{"mismo id", "[[#3]]", "[[#3]]"},
+		{"por nombre", "[[1]]", "[[1]]"},
+		{"en codigo", "`[[#1]]`", "`[[#1]]`"},
+	}
+	r := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.RemapWikiLinkIDs(tt.src, ids); got != tt.want {
+				t.Errorf("RemapWikiLinkIDs(%q) = %q, se esperaba %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -45,3 +45,23 @@ func Authenticator(jwtSecret []byte) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// AdminOnly es un middleware que permite el acceso solo a los usuarios de
+// admins. Se usa despues de Authenticator, que deja los claims en el contexto.
+func AdminOnly(admins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(admins))
+	for _, username := range admins {
+		allowed[username] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*auth.Claims)
+			if !ok || !allowed[claims.Username] {
+				http.Error(w, "No tienes permiso para acceder a esta página", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
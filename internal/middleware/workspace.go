@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Calevin/go_htmx_crud/internal/auth"
+	"github.com/Calevin/go_htmx_crud/internal/db"
+)
+
+const WorkspaceContextKey = contextKey("workspace")
+
+// WorkspaceCookie guarda el id del espacio de trabajo elegido. Sin la cookie
+// (o vacia) el usuario esta en su espacio personal.
+const WorkspaceCookie = "espacio"
+
+// Workspaces son los espacios de trabajo del usuario autenticado y el que
+// tiene elegido.
+type Workspaces struct {
+	// Current es el espacio elegido, o nil si esta en su espacio personal.
+	Current *db.ListWorkspacesForUserRow
+	List    []db.ListWorkspacesForUserRow
+}
+
+// LoadWorkspaces es un middleware que deja en el contexto los espacios de
+// trabajo del usuario. Se usa despues de Authenticator. Si la cookie apunta a
+// un espacio del que el usuario ya no es miembro, se vuelve al personal.
+func LoadWorkspaces(queries *db.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*auth.Claims)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := queries.GetUserByUsername(r.Context(), claims.Username)
+			if errors.Is(err, sql.ErrNoRows) {
+				// El usuario del token ya no existe
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Error al obtener el usuario", http.StatusInternalServerError)
+				return
+			}
+
+			list, err := queries.ListWorkspacesForUser(r.Context(), user.ID)
+			if err != nil {
+				http.Error(w, "Error al obtener los espacios de trabajo", http.StatusInternalServerError)
+				return
+			}
+
+			workspaces := &Workspaces{List: list}
+			if cookie, err := r.Cookie(WorkspaceCookie); err == nil && cookie.Value != "" {
+				id, _ := strconv.ParseInt(cookie.Value, 10, 64)
+				for i := range list {
+					if list[i].ID == id {
+						workspaces.Current = &list[i]
+						break
+					}
+				}
+				if workspaces.Current == nil {
+					log.Printf("El usuario %s ya no es miembro del espacio %q", user.Username, cookie.Value)
+					SetWorkspaceCookie(w, 0)
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), WorkspaceContextKey, workspaces)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// SetWorkspaceCookie cambia el espacio de trabajo elegido. 0 es el espacio personal.
+func SetWorkspaceCookie(w http.ResponseWriter, id int64) {
+	cookie := &http.Cookie{
+		Name:     WorkspaceCookie,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if id == 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Value = strconv.FormatInt(id, 10)
+	}
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,159 @@
+package notefile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrFrontMatter indica que el front matter del archivo no es YAML valido.
+var ErrFrontMatter = errors.New("front matter inválido")
+
+// Claves del front matter que se aceptan para el nombre y los tags. Ademas de
+// las que genera Encode se aceptan las de otras aplicaciones (Obsidian, Hugo, etc.).
+var (
+	nameKeys = []string{"nombre", "title", "titulo", "título", "name"}
+	tagKeys  = []string{"tags", "etiquetas"}
+)
+
+// Decode lee una nota de un archivo Markdown. El nombre se toma del front
+// matter o, si no esta, del primer titulo "# ..." o del nombre del archivo.
+// Solo se usan el nombre, los tags y el contenido; los demas campos se ignoran.
+func Decode(filename string, data []byte) (Note, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var n Note
+	front, body, ok := splitFrontMatter(src)
+	if ok {
+		var meta map[string]any
+		if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+			return n, fmt.Errorf("%w: %v", ErrFrontMatter, err)
+		}
+		n.Nombre = stringField(meta, nameKeys)
+		tags, err := tagsField(meta)
+		if err != nil {
+			return n, err
+		}
+		n.Tags = tags
+	}
+	n.Contenido = strings.TrimLeft(body, "\n")
+
+	if n.Nombre == "" {
+		n.Nombre = heading(n.Contenido)
+	}
+	if n.Nombre == "" {
+		n.Nombre = strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	}
+	n.Nombre = strings.TrimSpace(n.Nombre)
+	if n.Nombre == "" {
+		return n, errors.New("la nota no tiene nombre")
+	}
+	return n, nil
+}
+
+// splitFrontMatter separa el bloque entre las lineas "---" del principio del
+// resto del archivo. ok es false si el archivo no tiene front matter.
+func splitFrontMatter(src string) (front, body string, ok bool) {
+	rest, found := strings.CutPrefix(src, "---\n")
+	if !found {
+		return "", src, false
+	}
+	for _, end := range []string{"---", "..."} {
+		if strings.HasPrefix(rest, end+"\n") || rest == end {
+			return "", strings.TrimPrefix(rest[len(end):], "\n"), true
+		}
+		if i := strings.Index(rest, "\n"+end+"\n"); i >= 0 {
+			return rest[:i], rest[i+len(end)+2:], true
+		}
+		if strings.HasSuffix(rest, "\n"+end) {
+			return rest[:len(rest)-len(end)-1], "", true
+		}
+	}
+	return "", src, false
+}
+
+// stringField devuelve el valor de la primera clave de keys que sea un texto.
+func stringField(meta map[string]any, keys []string) string {
+	for _, key := range keys {
+		if v, ok := meta[key]; ok && v != nil {
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+	}
+	return ""
+}
+
+// tagsField lee los tags del front matter. Se aceptan una lista de nombres,
+// una lista de objetos con nombre y color (como los escribe Encode) o un texto
+// con los nombres separados por comas o espacios. A los nombres sueltos se les
+// quita el # del principio (#tag, como en Obsidian); los de los objetos se
+// usan tal cual. Los tags repetidos se descartan.
+func tagsField(meta map[string]any) ([]Tag, error) {
+	var value any
+	for _, key := range tagKeys {
+		if v, ok := meta[key]; ok {
+			value = v
+			break
+		}
+	}
+
+	var tags []Tag
+	switch v := value.(type) {
+	case nil:
+	case string:
+		sep := ","
+		if !strings.Contains(v, sep) {
+			sep = " "
+		}
+		for _, name := range strings.Split(v, sep) {
+			tags = append(tags, Tag{Nombre: trimHash(name)})
+		}
+	case []any:
+		for _, item := range v {
+			switch t := item.(type) {
+			case map[string]any:
+				tags = append(tags, Tag{
+					Nombre: stringField(t, []string{"nombre", "name"}),
+					Color:  stringField(t, []string{"color"}),
+				})
+			case nil:
+			default:
+				tags = append(tags, Tag{Nombre: trimHash(fmt.Sprint(t))})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: tags debe ser una lista", ErrFrontMatter)
+	}
+
+	seen := make(map[string]bool)
+	out := tags[:0]
+	for _, t := range tags {
+		t.Nombre = strings.TrimSpace(t.Nombre)
+		key := strings.ToLower(t.Nombre)
+		if t.Nombre == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// trimHash quita el # del principio de un nombre de tag.
+func trimHash(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), "#")
+}
+
+// heading devuelve el texto del primer titulo de nivel 1 si es la primera
+// linea no vacia del contenido.
+func heading(contenido string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(contenido, "\n"), "\n")
+	if title, ok := strings.CutPrefix(line, "# "); ok {
+		return strings.TrimSpace(title)
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+// Package notefile convierte notas a archivos Markdown con front matter YAML,
+// el formato usado para exportarlas e importarlas.
+package notefile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Tag es un tag de la nota con su color.
+type Tag struct {
+	Nombre string
+	Color  string
+}
+
+// Note es una nota tal como se guarda en el archivo.
+type Note struct {
+	ID        int64
+	Nombre    string
+	Contenido string
+	Tags      []Tag
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Los campos opcionales se omiten del front matter si estan vacios.
+	DueAt      *time.Time
+	PinnedAt   *time.Time
+	ArchivedAt *time.Time
+}
+
+// Encode escribe la nota como Markdown, con los metadatos en un bloque de
+// front matter YAML al principio.
+func Encode(w io.Writer, n Note) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("---\n")
+	fmt.Fprintf(bw, "id: %d\n", n.ID)
+	fmt.Fprintf(bw, "nombre: %s\n", quote(n.Nombre))
+	if len(n.Tags) == 0 {
+		bw.WriteString("tags: []\n")
+	} else {
+		bw.WriteString("tags:\n")
+		for _, t := range n.Tags {
+			fmt.Fprintf(bw, "  - nombre: %s\n", quote(t.Nombre))
+			if t.Color != "" {
+				fmt.Fprintf(bw, "    color: %s\n", quote(t.Color))
+			}
+		}
+	}
+	fmt.Fprintf(bw, "created_at: %s\n", formatTime(n.CreatedAt))
+	fmt.Fprintf(bw, "updated_at: %s\n", formatTime(n.UpdatedAt))
+	if n.DueAt != nil {
+		fmt.Fprintf(bw, "due_at: %s\n", formatTime(*n.DueAt))
+	}
+	if n.PinnedAt != nil {
+		fmt.Fprintf(bw, "pinned_at: %s\n", formatTime(*n.PinnedAt))
+	}
+	if n.ArchivedAt != nil {
+		fmt.Fprintf(bw, "archived_at: %s\n", formatTime(*n.ArchivedAt))
+	}
+	bw.WriteString("---\n\n")
+
+	bw.WriteString(n.Contenido)
+	if n.Contenido != "" && !strings.HasSuffix(n.Contenido, "\n") {
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
+
+// Filename devuelve un nombre de archivo unico para la nota, con su id y el
+// nombre normalizado (ej. "0012-lista-de-compras.md").
+func Filename(n Note) string {
+	slug := Slug(n.Nombre)
+	if slug == "" {
+		return fmt.Sprintf("%04d.md", n.ID)
+	}
+	return fmt.Sprintf("%04d-%s.md", n.ID, slug)
+}
+
+// maxSlugLength es el largo maximo, en caracteres, del nombre normalizado.
+const maxSlugLength = 60
+
+// Slug normaliza s para usarlo en un nombre de archivo: minusculas, letras y
+// numeros, con guiones en lugar de los demas caracteres.
+func Slug(s string) string {
+	var b strings.Builder
+	dash := false
+	count := 0
+	for _, r := range strings.ToLower(s) {
+		if count >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+				count++
+			}
+			b.WriteRune(r)
+			count++
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// quote devuelve s como escalar YAML entre comillas dobles. Los strings de
+// JSON son escalares validos de YAML, con los mismos escapes. No se escapan
+// <, > y & como hace json.Marshal, para que se lean igual en el archivo.
+func quote(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatTime formatea t en RFC 3339 (UTC), que YAML reconoce como timestamp.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,181 @@
+package notefile
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	due := created.Add(48 * time.Hour)
+
+	tests := []struct {
+		name string
+		note Note
+	}{
+		{"simple", Note{Nombre: "Lista de compras", Contenido: "- pan\n- leche\n"}},
+		{"sin contenido", Note{Nombre: "Vacía"}},
+		{"dos puntos", Note{Nombre: "Reunión: 10:30", Contenido: "clave: valor\n"}},
+		{"numeral", Note{Nombre: "#1 # prioridad", Contenido: "# Título\n\ntexto\n"}},
+		{"comillas", Note{Nombre: `Dijo "hola" y 'chau'`, Contenido: "texto\n"}},
+		{"salto de linea", Note{Nombre: "primera\nsegunda", Contenido: "texto\n"}},
+		{"parece YAML", Note{Nombre: "null", Contenido: "true\n"}},
+		{"parece numero", Note{Nombre: "2026", Contenido: "x\n"}},
+		{"separadores en el contenido", Note{Nombre: "con ---", Contenido: "arriba\n---\nabajo\n...\n"}},
+		{"barra invertida y unicode", Note{Nombre: `C:\notas ñandú 🗒️`, Contenido: "ok\n"}},
+		{"HTML", Note{Nombre: "<b>a & b</b>", Contenido: "<p>\n"}},
+		{
+			"con tags y fechas",
+			Note{
+				ID:        12,
+				Nombre:    "Proyecto",
+				Contenido: "texto\n",
+				Tags: []Tag{
+					{Nombre: "en curso", Color: "#ffcc00"},
+					{Nombre: "#urgente"},
+					{Nombre: "a: b, \"c\""},
+				},
+				CreatedAt: created,
+				UpdatedAt: created,
+				DueAt:     &due,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, tt.note); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got, err := Decode(Filename(tt.note), buf.Bytes())
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, buf.String())
+			}
+			if got.Nombre != tt.note.Nombre {
+				t.Errorf("Nombre = %q, se esperaba %q", got.Nombre, tt.note.Nombre)
+			}
+			if got.Contenido != tt.note.Contenido {
+				t.Errorf("Contenido = %q, se esperaba %q", got.Contenido, tt.note.Contenido)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.note.Tags) {
+				t.Errorf("Tags = %#v, se esperaba %#v", got.Tags, tt.note.Tags)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"hola", `"hola"`},
+		{`dijo "hola"`, `"dijo \"hola\""`},
+		{"a\nb", `"a\nb"`},
+		{"<b>a & b</b>", `"<b>a & b</b>"`},
+	}
+	for _, tt := range tests {
+		if got := quote(tt.in); got != tt.want {
+			t.Errorf("quote(%q) = %s, se esperaba %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+		want     Note
+	}{
+		{
+			"sin front matter, nombre del titulo",
+			"nota.md",
+			"# Ideas\n\nuna idea\n",
+			Note{Nombre: "Ideas", Contenido: "# Ideas\n\nuna idea\n"},
+		},
+		{
+			"sin front matter ni titulo, nombre del archivo",
+			"carpeta/mi nota.md",
+			"texto\n",
+			Note{Nombre: "mi nota", Contenido: "texto\n"},
+		},
+		{
+			"BOM y CRLF",
+			"nota.md",
+			"\xef\xbb\xbf---\r\ntitle: Hola\r\n---\r\ntexto\r\n",
+			Note{Nombre: "Hola", Contenido: "texto\n"},
+		},
+		{
+			"tags de Obsidian como texto",
+			"nota.md",
+			"---\ntitle: x\ntags: \"#uno, #dos, uno\"\n---\n",
+			Note{Nombre: "x", Tags: []Tag{{Nombre: "uno"}, {Nombre: "dos"}}},
+		},
+		{
+			"tags como lista",
+			"nota.md",
+			"---\nnombre: x\netiquetas: [a, \"#b\", A]\n---\n",
+			Note{Nombre: "x", Tags: []Tag{{Nombre: "a"}, {Nombre: "b"}}},
+		},
+		{
+			"front matter vacio",
+			"vacia.md",
+			"---\n---\ncontenido\n",
+			Note{Nombre: "vacia", Contenido: "contenido\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.filename, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %#v, se esperaba %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"YAML invalido", "---\nnombre: [\n---\n", ErrFrontMatter},
+		{"tags no es lista", "---\nnombre: x\ntags: {a: 1}\n---\n", ErrFrontMatter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode("nota.md", []byte(tt.data))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, se esperaba %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Decode(".md", []byte("   \n")); err == nil {
+		t.Error("una nota sin nombre deberia dar error")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Lista de Compras", "lista-de-compras"},
+		{"  ¡Hola, mundo!  ", "hola-mundo"},
+		{"Reunión: 10:30", "reunión-10-30"},
+		{"###", ""},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.in); got != tt.want {
+			t.Errorf("Slug(%q) = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package rank genera claves de orden fraccionarias: cadenas que se comparan
+// lexicograficamente y entre dos claves cualesquiera siempre se puede generar
+// una nueva. Asi mover un elemento solo requiere actualizar su propia clave.
+//
+// Cada clave tiene una parte entera de largo variable, cuyo primer caracter
+// indica el largo ('a'-'z' positivos, 'A'-'Z' negativos), seguida de una parte
+// fraccionaria opcional. Agregar al principio o al final solo incrementa o
+// decrementa la parte entera, por lo que las claves crecen muy lentamente.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits son los digitos de las claves, en orden ASCII para que la comparacion
+// de cadenas coincida con el orden numerico.
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// smallestInteger es la menor parte entera posible; no se puede decrementar.
+var smallestInteger = "A" + strings.Repeat(digits[:1], 26)
+
+var (
+	// ErrInvalidKey se devuelve cuando una clave no tiene el formato esperado.
+	ErrInvalidKey = errors.New("rank: clave inválida")
+	// ErrInvalidRange se devuelve cuando las claves no estan en orden.
+	ErrInvalidRange = errors.New("rank: rango de claves inválido")
+	// ErrExhausted se devuelve cuando no hay mas claves en esa direccion.
+	ErrExhausted = errors.New("rank: no hay más claves disponibles")
+)
+
+// First es la clave inicial, para cuando la lista esta vacia.
+const First = "a0"
+
+// Between devuelve una clave que queda entre a y b. Una clave vacia representa
+// el principio (a) o el final (b) de la lista.
+func Between(a, b string) (string, error) {
+	if a != "" && !Valid(a) || b != "" && !Valid(b) {
+		return "", ErrInvalidKey
+	}
+	if a != "" && b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+
+	if a == "" {
+		if b == "" {
+			return First, nil
+		}
+		ib := integerPart(b)
+		fb := b[len(ib):]
+		if ib == smallestInteger {
+			return ib + midpoint("", fb), nil
+		}
+		// b tiene parte fraccionaria: su parte entera sola ya es menor
+		if ib < b {
+			return ib, nil
+		}
+		res, ok := decrement(ib)
+		if !ok {
+			return "", ErrExhausted
+		}
+		return res, nil
+	}
+
+	ia := integerPart(a)
+	fa := a[len(ia):]
+	if b == "" {
+		res, ok := increment(ia)
+		if !ok {
+			return ia + midpoint(fa, ""), nil
+		}
+		return res, nil
+	}
+
+	ib := integerPart(b)
+	fb := b[len(ib):]
+	if ia == ib {
+		return ia + midpoint(fa, fb), nil
+	}
+	res, ok := increment(ia)
+	if !ok {
+		return "", ErrExhausted
+	}
+	if res < b {
+		return res, nil
+	}
+	return ia + midpoint(fa, ""), nil
+}
+
+// Valid indica si key es una clave bien formada.
+func Valid(key string) bool {
+	if key == "" || key == smallestInteger {
+		return false
+	}
+	n := integerLength(key[0])
+	if n == 0 || n > len(key) {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	// La parte fraccionaria no puede terminar en cero, para que siempre exista una clave menor
+	return len(key) == n || key[len(key)-1] != digits[0]
+}
+
+// integerLength devuelve el largo de la parte entera segun su primer caracter, o 0 si no es valido.
+func integerLength(head byte) int {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2
+	}
+	return 0
+}
+
+// integerPart devuelve la parte entera de una clave valida.
+func integerPart(key string) string {
+	return key[:integerLength(key[0])]
+}
+
+// increment suma uno a la parte entera x. Devuelve false si ya es la mayor.
+func increment(x string) (string, bool) {
+	head, digs := x[0], []byte(x[1:])
+	carry := true
+	for i := len(digs) - 1; carry && i >= 0; i-- {
+		d := strings.IndexByte(digits, digs[i]) + 1
+		if d == len(digits) {
+			digs[i] = digits[0]
+		} else {
+			digs[i] = digits[d]
+			carry = false
+		}
+	}
+	if !carry {
+		return string(head) + string(digs), true
+	}
+
+	// Se necesita un digito mas (o uno menos, del lado negativo)
+	switch head {
+	case 'Z':
+		return "a" + digits[:1], true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digs = append(digs, digits[0])
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
+
+// decrement resta uno a la parte entera x. Devuelve false si ya es la menor.
+func decrement(x string) (string, bool) {
+	head, digs := x[0], []byte(x[1:])
+	borrow := true
+	for i := len(digs) - 1; borrow && i >= 0; i-- {
+		d := strings.IndexByte(digits, digs[i]) - 1
+		if d == -1 {
+			digs[i] = digits[len(digits)-1]
+		} else {
+			digs[i] = digits[d]
+			borrow = false
+		}
+	}
+	if !borrow {
+		return string(head) + string(digs), true
+	}
+
+	switch head {
+	case 'a':
+		return "Z" + digits[len(digits)-1:], true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digs = append(digs, digits[len(digits)-1])
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
+
+// midpoint calcula una parte fraccionaria entre a y b, con a < b. Un b vacio
+// no tiene limite superior.
+func midpoint(a, b string) string {
+	if b != "" {
+		// Se saltea el prefijo comun, completando a con ceros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+
+	// Hay lugar para un digito entre los dos
+	if hi-lo > 1 {
+		return string(digits[(lo+hi+1)/2])
+	}
+	// Los digitos son consecutivos: si b tiene mas digitos, su primer digito
+	// ya es mayor que a y menor que b
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[lo]) + midpoint(suffix(a, 1), "")
+}
+
+// digitAt devuelve el digito i de key, o cero si key es mas corta.
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+// suffix devuelve key a partir de la posicion i, o vacio si key es mas corta.
+func suffix(key string, i int) string {
+	if i < len(key) {
+		return key[i:]
+	}
+	return ""
+}
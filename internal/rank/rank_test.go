@@ -0,0 +1,112 @@
+package rank
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"lista vacia", "", ""},
+		{"antes de la primera", "", "a0"},
+		{"despues de la ultima", "a0", ""},
+		{"entre enteros consecutivos", "a0", "a1"},
+		{"entre enteros separados", "a0", "a5"},
+		{"con parte fraccionaria", "a0V", "a1"},
+		{"fracciones consecutivas", "a0V", "a0W"},
+		{"antes de una fraccion", "", "a0V"},
+		{"entre negativo y positivo", "Zz", "a0"},
+		{"despues del mayor de un largo", "az", ""},
+		{"antes del menor de un largo", "", "b00"},
+		{"claves del backfill", "h00000001", "h00000002"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("Between(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if !Valid(got) {
+				t.Errorf("Between(%q, %q) = %q, no es una clave válida", tt.a, tt.b, got)
+			}
+			if tt.a != "" && got <= tt.a || tt.b != "" && got >= tt.b {
+				t.Errorf("Between(%q, %q) = %q, fuera del rango", tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want error
+	}{
+		{"iguales", "a0", "a0", ErrInvalidRange},
+		{"invertidas", "a1", "a0", ErrInvalidRange},
+		{"fraccion invertida", "a0W", "a0V", ErrInvalidRange},
+		{"cabecera invalida", "!0", "", ErrInvalidKey},
+		{"largo incorrecto", "", "b0", ErrInvalidKey},
+		{"fraccion terminada en cero", "a0V0", "", ErrInvalidKey},
+		{"digito invalido", "a0-", "", ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Between(tt.a, tt.b)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Between(%q, %q) error = %v, se esperaba %v", tt.a, tt.b, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestBetweenSequence inserta muchas claves al principio, al final y siempre
+// en el mismo hueco, y verifica que el orden se mantiene.
+func TestBetweenSequence(t *testing.T) {
+	tests := []struct {
+		name string
+		next func(keys []string) (string, string)
+	}{
+		{"al principio", func(keys []string) (string, string) { return "", keys[0] }},
+		{"al final", func(keys []string) (string, string) { return keys[len(keys)-1], "" }},
+		{"despues de la primera", func(keys []string) (string, string) {
+			if len(keys) == 1 {
+				return keys[0], ""
+			}
+			return keys[0], keys[1]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := []string{First}
+			for i := 0; i < 1000; i++ {
+				a, b := tt.next(keys)
+				key, err := Between(a, b)
+				if err != nil {
+					t.Fatalf("paso %d: Between(%q, %q): %v", i, a, b, err)
+				}
+				keys = insertSorted(keys, key)
+			}
+			for i := 1; i < len(keys); i++ {
+				if keys[i-1] >= keys[i] {
+					t.Fatalf("claves fuera de orden: %q >= %q", keys[i-1], keys[i])
+				}
+			}
+		})
+	}
+}
+
+// insertSorted agrega key a keys manteniendo el orden.
+func insertSorted(keys []string, key string) []string {
+	i := 0
+	for i < len(keys) && keys[i] < key {
+		i++
+	}
+	keys = append(keys, "")
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	return keys
+}
@@ -0,0 +1,107 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Reminder es el aviso de vencimiento de una nota.
+type Reminder struct {
+	NoteID int64     `json:"note_id"`
+	Nombre string    `json:"nombre"`
+	DueAt  time.Time `json:"due_at"`
+}
+
+// Notifier envia los recordatorios. Las implementaciones deben devolver error
+// si el envio falla, para que el recordatorio se reintente.
+type Notifier interface {
+	Notify(ctx context.Context, r Reminder) error
+}
+
+// LogNotifier escribe los recordatorios en el log del servidor.
+type LogNotifier struct{}
+
+// Notify implementa Notifier.
+func (LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	log.Printf("Recordatorio: la nota %d %q vence el %s", r.NoteID, r.Nombre, r.DueAt.Local().Format("02/01/2006 15:04"))
+	return nil
+}
+
+// SMTPNotifier envia los recordatorios por email.
+type SMTPNotifier struct {
+	// Addr es el servidor SMTP en formato host:puerto.
+	Addr string
+	// Username y Password son opcionales; si no se definen no se autentica.
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify implementa Notifier.
+func (n SMTPNotifier) Notify(ctx context.Context, r Reminder) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host := n.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	subject := fmt.Sprintf("Recordatorio: %s", r.Nombre)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	fmt.Fprintf(&msg, "La nota \"%s\" vence el %s.\r\n", r.Nombre, r.DueAt.Local().Format("02/01/2006 15:04"))
+
+	return smtp.SendMail(n.Addr, auth, n.From, n.To, msg.Bytes())
+}
+
+// WebhookNotifier envia los recordatorios como JSON a una URL por POST.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implementa Notifier.
+func (n WebhookNotifier) Notify(ctx context.Context, r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook respondió %s", resp.Status)
+	}
+	return nil
+}
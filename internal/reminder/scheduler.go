@@ -0,0 +1,128 @@
+// Package reminder envia los recordatorios de vencimiento de las notas.
+//
+// Cada recordatorio se guarda en la tabla reminders, con una fila unica por
+// nota y momento de aviso. Antes de enviarlo se reserva con un UPDATE
+// condicional sobre claimed_at, asi dos procesos no lo envian a la vez, y solo
+// se marca como enviado (sent_at) cuando el envio termina bien. Si el envio
+// falla se libera para reintentarlo en la siguiente pasada, hasta MaxAttempts
+// intentos. Si el proceso se corta con un recordatorio reservado, la reserva
+// vence a los LeaseTimeout y se vuelve a tomar, tambien despues de reiniciar.
+//
+// El unico caso en que un aviso puede llegar dos veces es que el proceso se
+// corte entre el envio y la marca de enviado: se prefiere eso a perderlo.
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Calevin/go_htmx_crud/internal/db"
+)
+
+// MaxAttempts es la cantidad de intentos de envio de un recordatorio.
+const MaxAttempts = 5
+
+// LeaseTimeout es cuanto dura la reserva de un recordatorio. Tiene que ser
+// bastante mas que lo que tarda un envio.
+const LeaseTimeout = 10 * time.Minute
+
+// Schedule programa el recordatorio de una nota que vence en dueAt, avisando
+// lead antes. Los recordatorios pendientes anteriores de la nota se descartan.
+// Si dueAt no es valido la nota queda sin recordatorio.
+func Schedule(ctx context.Context, queries *db.Queries, noteID int64, dueAt sql.NullTime, lead time.Duration) error {
+	if err := queries.DeletePendingReminders(ctx, noteID); err != nil {
+		return err
+	}
+	if !dueAt.Valid {
+		return nil
+	}
+
+	// Si el recordatorio ya se envio para esta fecha no se vuelve a crear
+	return queries.CreateReminder(ctx, db.CreateReminderParams{
+		NoteID: noteID,
+		FireAt: dueAt.Time.Add(-lead).UTC(),
+	})
+}
+
+// Run envia los recordatorios pendientes cuyo momento de aviso ya paso.
+// Devuelve la cantidad de recordatorios enviados.
+func Run(ctx context.Context, queries *db.Queries, notifier Notifier) (int, error) {
+	now := time.Now().UTC()
+	stale := sql.NullTime{Time: now.Add(-LeaseTimeout), Valid: true}
+	due, err := queries.ListDueReminders(ctx, db.ListDueRemindersParams{
+		Stale:       stale,
+		Now:         now,
+		MaxAttempts: MaxAttempts,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, r := range due {
+		// Se reserva el recordatorio; si otro proceso ya lo tomo se saltea
+		claimed, err := queries.ClaimReminder(ctx, db.ClaimReminderParams{
+			ClaimedAt: sql.NullTime{Time: now, Valid: true},
+			ID:        r.ID,
+			Stale:     stale,
+		})
+		if err != nil {
+			return sent, err
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		reminder := Reminder{
+			NoteID: r.NoteID,
+			Nombre: r.Nombre,
+			DueAt:  r.DueAt.Time,
+		}
+		if err := notifier.Notify(ctx, reminder); err != nil {
+			log.Printf("Error enviando el recordatorio %d (intento %d): %v", r.ID, r.Attempts+1, err)
+			releaseErr := queries.ReleaseReminder(ctx, db.ReleaseReminderParams{
+				ID:        r.ID,
+				LastError: sql.NullString{String: err.Error(), Valid: true},
+			})
+			if releaseErr != nil {
+				return sent, releaseErr
+			}
+			continue
+		}
+
+		err = queries.MarkReminderSent(ctx, db.MarkReminderSentParams{
+			SentAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			ID:     r.ID,
+		})
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartScheduler ejecuta Run al iniciar y luego cada interval, hasta que se cancele ctx.
+func StartScheduler(ctx context.Context, queries *db.Queries, notifier Notifier, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sent, err := Run(ctx, queries, notifier)
+			if err != nil {
+				log.Printf("Error enviando recordatorios: %v", err)
+			} else if sent > 0 {
+				log.Printf("Recordatorios: %d enviados", sent)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
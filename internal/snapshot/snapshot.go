@@ -0,0 +1,316 @@
+// Package snapshot genera copias completas del archivo SQLite con VACUUM INTO,
+// sin detener el servidor, y las restaura.
+//
+// Cada copia se verifica con PRAGMA integrity_check antes de darla por buena.
+// Las copias viejas se eliminan segun la cantidad y la antiguedad configuradas.
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Prefijo, sufijo y formato de fecha de los nombres de las copias
+// (ej. "crud-20261019T053200Z.db"). La fecha del nombre es la de la copia.
+const (
+	namePrefix = "crud-"
+	nameSuffix = ".db"
+	timeLayout = "20060102T150405Z"
+)
+
+// ErrNotFound indica que la copia pedida no existe.
+var ErrNotFound = errors.New("la copia no existe")
+
+// Snapshot es una copia de la base de datos.
+type Snapshot struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// Manager crea, lista y elimina las copias de un directorio.
+type Manager struct {
+	// Dir es el directorio de las copias.
+	Dir string
+	// Keep es la cantidad de copias a conservar; 0 para no limitarla.
+	Keep int
+	// MaxAge es la antiguedad maxima de las copias; 0 para no limitarla.
+	// La copia mas reciente nunca se elimina.
+	MaxAge time.Duration
+
+	// mu evita que se hagan dos copias a la vez (ej. la programada y una manual).
+	mu sync.Mutex
+}
+
+// Create hace una copia de la base abierta en conn, verifica su integridad y
+// elimina las copias que exceden la retencion.
+func (m *Manager) Create(ctx context.Context, conn *sql.DB) (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return Snapshot{}, err
+	}
+
+	now := time.Now().UTC()
+	name := namePrefix + now.Format(timeLayout) + nameSuffix
+	path := filepath.Join(m.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return Snapshot{}, fmt.Errorf("ya existe la copia %s", name)
+	}
+
+	// Se escribe con otro nombre y se renombra recien despues de verificarla,
+	// asi nunca queda una copia incompleta con un nombre valido
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, fmt.Errorf("error copiando la base de datos: %w", err)
+	}
+	if err := Verify(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Snapshot{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s := Snapshot{Name: name, Path: path, Size: info.Size(), CreatedAt: now}
+
+	if _, err := m.prune(now); err != nil {
+		log.Printf("Error eliminando copias viejas: %v", err)
+	}
+	return s, nil
+}
+
+// List devuelve las copias del directorio, de la mas nueva a la mas vieja.
+func (m *Manager) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(m.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, namePrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, nameSuffix)
+		if !ok {
+			continue
+		}
+		createdAt, err := time.Parse(timeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:      name,
+			Path:      filepath.Join(m.Dir, name),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Get devuelve la copia con ese nombre. Solo se aceptan nombres de copias,
+// no rutas, para no exponer otros archivos.
+func (m *Manager) Get(name string) (Snapshot, error) {
+	snapshots, err := m.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, s := range snapshots {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Snapshot{}, ErrNotFound
+}
+
+// Latest devuelve la copia mas reciente; ok es false si no hay ninguna.
+func (m *Manager) Latest() (s Snapshot, ok bool, err error) {
+	snapshots, err := m.List()
+	if err != nil || len(snapshots) == 0 {
+		return Snapshot{}, false, err
+	}
+	return snapshots[0], true, nil
+}
+
+// prune elimina las copias que sobran segun Keep y MaxAge y devuelve sus nombres.
+func (m *Manager) prune(now time.Time) ([]string, error) {
+	snapshots, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i, s := range snapshots {
+		if i == 0 {
+			continue
+		}
+		tooMany := m.Keep > 0 && i >= m.Keep
+		tooOld := m.MaxAge > 0 && now.Sub(s.CreatedAt) > m.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(s.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, s.Name)
+	}
+	return removed, nil
+}
+
+// Verify abre la base de path en solo lectura y ejecuta PRAGMA integrity_check.
+func Verify(ctx context.Context, path string) error {
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("error verificando la copia: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error verificando la copia: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("la copia está dañada: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Restore reemplaza la base de dbPath por la copia de snapshotPath, despues de
+// verificarla. La base actual se conserva junto a la original con el sufijo
+// ".antes-de-restaurar-<fecha>". El servidor tiene que estar detenido.
+func Restore(ctx context.Context, snapshotPath, dbPath string) (previous string, err error) {
+	if err := Verify(ctx, snapshotPath); err != nil {
+		return "", err
+	}
+
+	// Primero se copia al lado de la base, asi el reemplazo final es un rename
+	tmp := dbPath + ".restaurando"
+	if err := copyFile(snapshotPath, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".antes-de-restaurar-" + time.Now().UTC().Format(timeLayout)
+		if err := os.Rename(dbPath, previous); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+		// El journal de la base anterior no debe aplicarse sobre la restaurada
+		for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+			if _, err := os.Stat(dbPath + suffix); err == nil {
+				if err := os.Rename(dbPath+suffix, previous+suffix); err != nil {
+					os.Remove(tmp)
+					return previous, err
+				}
+			}
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return previous, err
+	}
+	return previous, nil
+}
+
+// copyFile copia src en dst y sincroniza dst con el disco.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// StartScheduler hace una copia cada interval en segundo plano hasta que se
+// cancela ctx. Al iniciar se hace una copia si la ultima tiene mas de interval.
+func StartScheduler(ctx context.Context, conn *sql.DB, m *Manager, interval time.Duration) {
+	go func() {
+		next := time.Duration(0)
+		if latest, ok, err := m.Latest(); err == nil && ok {
+			next = max(interval-time.Since(latest.CreatedAt), 0)
+		}
+
+		timer := time.NewTimer(next)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			if s, err := m.Create(ctx, conn); err != nil {
+				log.Printf("Error creando la copia programada: %v", err)
+			} else {
+				log.Printf("Copia de la base creada: %s", s.Name)
+			}
+			timer.Reset(interval)
+		}
+	}()
+}
@@ -0,0 +1,115 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeSnapshots crea copias vacias en dir, una por cada antiguedad respecto
+// de now, y devuelve sus nombres.
+func writeSnapshots(t *testing.T, dir string, now time.Time, ages ...time.Duration) []string {
+	t.Helper()
+	var names []string
+	for _, age := range ages {
+		name := namePrefix + now.Add(-age).Format(timeLayout) + nameSuffix
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	ages := []time.Duration{0, day, 2 * day, 3 * day}
+
+	tests := []struct {
+		name    string
+		keep    int
+		maxAge  time.Duration
+		ages    []time.Duration
+		removed []int
+	}{
+		{"sin limites", 0, 0, ages, nil},
+		{"por cantidad", 2, 0, ages, []int{2, 3}},
+		{"cantidad mayor a las copias", 10, 0, ages, nil},
+		{"por antiguedad", 0, 36 * time.Hour, ages, []int{2, 3}},
+		{"justo en el limite se conserva", 0, day, ages, []int{2, 3}},
+		{"cantidad y antiguedad", 3, 36 * time.Hour, ages, []int{2, 3}},
+		{"la mas reciente nunca se elimina", 0, time.Hour, []time.Duration{3 * day, 4 * day}, []int{1}},
+		{"keep 1", 1, 0, ages, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			names := writeSnapshots(t, dir, now, tt.ages...)
+			// Otros archivos del directorio no se tocan
+			other := filepath.Join(dir, "crud-notas.db")
+			if err := os.WriteFile(other, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := &Manager{Dir: dir, Keep: tt.keep, MaxAge: tt.maxAge}
+			removed, err := m.prune(now)
+			if err != nil {
+				t.Fatalf("prune: %v", err)
+			}
+
+			var want []string
+			for _, i := range tt.removed {
+				want = append(want, names[i])
+			}
+			if !reflect.DeepEqual(removed, want) {
+				t.Errorf("se eliminaron %v, se esperaba %v", removed, want)
+			}
+			snapshots, err := m.List()
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(snapshots) != len(names)-len(want) {
+				t.Errorf("quedaron %d copias, se esperaban %d", len(snapshots), len(names)-len(want))
+			}
+			if _, err := os.Stat(other); err != nil {
+				t.Errorf("se elimino un archivo que no es una copia: %v", err)
+			}
+		})
+	}
+}
+
+func TestListOrderAndGet(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	names := writeSnapshots(t, dir, now, 2*time.Hour, 0, time.Hour)
+
+	m := &Manager{Dir: dir}
+	snapshots, err := m.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var got []string
+	for _, s := range snapshots {
+		got = append(got, s.Name)
+	}
+	if want := []string{names[1], names[2], names[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, se esperaba %v", got, want)
+	}
+
+	latest, ok, err := m.Latest()
+	if err != nil || !ok || latest.Name != names[1] {
+		t.Errorf("Latest = %v, %v, %v", latest.Name, ok, err)
+	}
+	if _, err := m.Get("../crud.db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get con una ruta: error = %v, se esperaba %v", err, ErrNotFound)
+	}
+
+	empty := &Manager{Dir: filepath.Join(dir, "no-existe")}
+	if snapshots, err := empty.List(); err != nil || len(snapshots) != 0 {
+		t.Errorf("List sin directorio = %v, %v", snapshots, err)
+	}
+}
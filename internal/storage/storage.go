@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotFound se devuelve cuando el blob pedido no existe.
+var ErrNotFound = errors.New("storage: blob no encontrado")
+
+// BlobStore guarda contenido binario direccionado por su hash SHA-256.
+// Dos archivos iguales comparten el mismo blob.
+type BlobStore interface {
+	// Put guarda el contenido de r y devuelve su clave (el SHA-256 en hexadecimal) y su tamaño.
+	Put(ctx context.Context, r io.Reader) (key string, size int64, err error)
+	// Open abre el blob con la clave dada. Devuelve ErrNotFound si no existe.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete borra el blob. No es un error borrar un blob que no existe.
+	Delete(ctx context.Context, key string) error
+}
+
+// keyRegexp valida que la clave sea un SHA-256 en hexadecimal, para no armar
+// rutas con datos arbitrarios.
+var keyRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LocalStore es un BlobStore que guarda los blobs en el sistema de archivos,
+// en root/ab/abcdef... usando los dos primeros caracteres del hash como directorio.
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore crea el directorio root si no existe y devuelve el LocalStore.
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !keyRegexp.MatchString(key) {
+		return "", fmt.Errorf("storage: clave inválida %q", key)
+	}
+	return filepath.Join(s.root, key[:2], key), nil
+}
+
+// Put escribe el contenido en un archivo temporal mientras calcula el hash y
+// luego lo mueve a su ruta definitiva.
+func (s *LocalStore) Put(ctx context.Context, r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.root, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	// Si algo falla se borra el temporal. Despues del Rename no existe y Remove no hace nada.
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path, err := s.path(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Si el blob ya existe no hace falta volver a escribirlo
+	if _, err := os.Stat(path); err == nil {
+		return key, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+// Open abre el archivo del blob.
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete borra el archivo del blob.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// MaxSize es el tamaño maximo (ancho o alto) de las miniaturas en pixeles.
+const MaxSize = 320
+
+// maxPixels limita el tamaño de las imagenes que se decodifican, para que una
+// imagen muy comprimida no consuma toda la memoria: decodificada ocupa hasta
+// 4 bytes por pixel, unos 64 MB, y puede haber varias subidas a la vez.
+const maxPixels = 16_000_000
+
+// ErrUnsupported se devuelve cuando el formato no admite miniaturas.
+var ErrUnsupported = errors.New("thumbnail: formato no soportado")
+
+// Supported indica si se pueden generar miniaturas para el tipo MIME dado.
+func Supported(mediaType string) bool {
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Generate crea una miniatura de data que entra en un cuadrado de MaxSize pixeles,
+// respetando la orientacion EXIF de las fotos JPEG. Las imagenes JPEG se devuelven
+// como JPEG y el resto como PNG para conservar la transparencia.
+func Generate(data []byte) ([]byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("thumbnail: imagen demasiado grande (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	var src image.Image
+	switch format {
+	case "jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "gif":
+		// Solo se usa el primer cuadro de los GIF animados
+		src, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return nil, "", ErrUnsupported
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	thumb := resize(src, MaxSize)
+	if format == "jpeg" {
+		thumb = orient(thumb, exifOrientation(data))
+	}
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&buf, thumb)
+	return buf.Bytes(), "image/png", err
+}
+
+// resize escala src para que entre en un cuadrado de max pixeles, manteniendo la proporcion.
+// Las imagenes mas chicas no se agrandan.
+func resize(src image.Image, max int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return src
+	}
+
+	if w > h {
+		h = h * max / w
+		w = max
+	} else {
+		w = w * max / h
+		h = max
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// orient aplica la transformacion indicada por el valor EXIF Orientation (1 a 8).
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	// Las orientaciones 5 a 8 intercambian ancho y alto
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // espejo horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotacion 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // espejo vertical
+				sx, sy = x, h-1-y
+			case 5: // transpuesta
+				sx, sy = y, x
+			case 6: // rotacion 90 horaria
+				sx, sy = y, h-1-x
+			case 7: // transversa
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotacion 90 antihoraria
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,121 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/storage"
+)
+
+// DefaultRetentionDays es la cantidad de dias que una nota permanece en la papelera antes de purgarse.
+const DefaultRetentionDays = 30
+
+// Purge elimina definitivamente las notas que estan en la papelera hace mas de
+// retention, junto con los blobs de sus adjuntos que quedan sin usar.
+func Purge(ctx context.Context, queries *db.Queries, store storage.BlobStore, retention time.Duration) (int64, error) {
+	cutoff := sql.NullTime{Time: time.Now().UTC().Add(-retention), Valid: true}
+
+	hashes, err := queries.ListPurgeableAttachmentHashes(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := queries.PurgeTrashedNotes(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	RemoveOrphanBlobs(ctx, queries, store, hashes)
+	return purged, nil
+}
+
+// DeleteNote elimina definitivamente una nota y los blobs de sus adjuntos que quedan sin usar.
+func DeleteNote(ctx context.Context, queries *db.Queries, store storage.BlobStore, id int64) error {
+	attachments, err := queries.ListAttachmentsForNote(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Los adjuntos se borran en cascada con la nota
+	if err := queries.DeleteNote(ctx, id); err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, 2*len(attachments))
+	for _, a := range attachments {
+		hashes = append(hashes, a.Sha256)
+		if a.ThumbnailSha256.Valid {
+			hashes = append(hashes, a.ThumbnailSha256.String)
+		}
+	}
+	RemoveOrphanBlobs(ctx, queries, store, hashes)
+	return nil
+}
+
+// blobs serializa el borrado de blobs huerfanos con las subidas: las subidas
+// lo toman para lectura (pueden ser varias a la vez) y RemoveOrphanBlobs para
+// escritura.
+var blobs sync.RWMutex
+
+// HoldBlobs evita que se borren blobs hasta llamar a la funcion devuelta, que
+// se puede llamar mas de una vez. Se usa al subir un archivo, desde antes de
+// guardar el blob hasta que se crea el adjunto que lo referencia: como los
+// blobs se comparten, si no, borrar otro adjunto con el mismo contenido podria
+// no ver ninguna referencia y borrar el blob recien subido.
+func HoldBlobs() (release func()) {
+	blobs.RLock()
+	return sync.OnceFunc(blobs.RUnlock)
+}
+
+// RemoveOrphanBlobs borra los blobs que ya no estan referenciados por ningun adjunto.
+// Como los blobs se comparten entre adjuntos con el mismo contenido, solo se borran
+// cuando no queda ninguna referencia. Los errores se registran pero no se devuelven:
+// un blob huerfano no afecta el funcionamiento.
+func RemoveOrphanBlobs(ctx context.Context, queries *db.Queries, store storage.BlobStore, hashes []string) {
+	blobs.Lock()
+	defer blobs.Unlock()
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		count, err := queries.CountAttachmentsBySHA256(ctx, hash)
+		if err != nil {
+			log.Printf("Error verificando referencias del blob %s: %v", hash, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		if err := store.Delete(ctx, hash); err != nil {
+			log.Printf("Error borrando el blob %s: %v", hash, err)
+		}
+	}
+}
+
+// StartPurger ejecuta Purge al iniciar y luego cada interval, hasta que se cancele ctx.
+func StartPurger(ctx context.Context, queries *db.Queries, store storage.BlobStore, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := Purge(ctx, queries, store, retention)
+			if err != nil {
+				log.Printf("Error purgando la papelera: %v", err)
+			} else if purged > 0 {
+				log.Printf("Papelera: %d notas eliminadas definitivamente", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
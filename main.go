@@ -7,7 +7,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,8 +17,14 @@ import (
 
 	"github.com/Calevin/go_htmx_crud/database"
 	"github.com/Calevin/go_htmx_crud/internal/db"
+	"github.com/Calevin/go_htmx_crud/internal/flash"
 	"github.com/Calevin/go_htmx_crud/internal/handlers"
+	"github.com/Calevin/go_htmx_crud/internal/markdown"
 	authMiddleware "github.com/Calevin/go_htmx_crud/internal/middleware"
+	"github.com/Calevin/go_htmx_crud/internal/reminder"
+	"github.com/Calevin/go_htmx_crud/internal/snapshot"
+	"github.com/Calevin/go_htmx_crud/internal/storage"
+	"github.com/Calevin/go_htmx_crud/internal/trash"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -27,6 +35,9 @@ var templateFS embed.FS
 var staticFS embed.FS
 var tpl *template.Template
 
+// Renderer de Markdown compartido por los templates y la vista previa
+var mdRenderer = markdown.New()
+
 func init() {
 	funcMap := template.FuncMap{
 		"include": func(templateName string, data any) (template.HTML, error) {
@@ -34,6 +45,14 @@ func init() {
 			err := tpl.ExecuteTemplate(&buf, templateName, data)
 			return template.HTML(buf.String()), err
 		},
+		"hace":     handlers.TimeAgo,
+		"fecha":    handlers.FormatDateTime,
+		"markdown": mdRenderer.RenderNote,
+		"tareas":   mdRenderer.NoteTasks,
+		"tamano":   handlers.FormatSize,
+
+		// Los comentarios se sanitizan igual que las notas, pero sin cache ni casillas interactivas
+		"comentario": mdRenderer.Render,
 	}
 	tpl = template.New("").Funcs(funcMap)
 	tpl = template.Must(tpl.ParseFS(templateFS, "templates/*.html"))
@@ -45,6 +64,12 @@ func main() {
 		log.Println("No se encontró el archivo .env, usando variables de entorno del sistema.")
 	}
 
+	// Con argumentos se ejecuta un comando (ej. backup) en lugar de iniciar el servidor
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
 	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
 	if len(jwtSecret) == 0 {
 		log.Fatal("La variable de entorno JWT_SECRET no está definida.")
@@ -61,11 +86,46 @@ func main() {
 	// Creamos un usuario de prueba si no existe
 	createTestUser(ctx, queries)
 
+	// Almacenamiento de los archivos adjuntos
+	blobDir := os.Getenv("BLOB_DIR")
+	if blobDir == "" {
+		blobDir = "./blobs"
+	}
+	blobStore, err := storage.NewLocalStore(blobDir)
+	if err != nil {
+		log.Fatalf("Error iniciando el almacenamiento de adjuntos: %v", err)
+	}
+	maxAttachmentSize := int64(envInt("ATTACHMENT_MAX_MB", 10)) << 20
+
+	// Las notas borradas quedan en la papelera hasta que se purgan
+	trashRetention := time.Duration(envInt("TRASH_RETENTION_DAYS", trash.DefaultRetentionDays)) * 24 * time.Hour
+	trash.StartPurger(ctx, queries, blobStore, trashRetention, time.Hour)
+
+	// Recordatorios de vencimiento de las notas
+	reminderLead := time.Duration(envInt("REMINDER_LEAD_MINUTES", 0)) * time.Minute
+	reminder.StartScheduler(ctx, queries, newReminderNotifier(), time.Minute)
+
+	// Copias programadas de la base de datos
+	snapshots := newSnapshotManager()
+	if hours := envInt("SNAPSHOT_INTERVAL_HOURS", 24); hours > 0 {
+		snapshot.StartScheduler(ctx, conn, snapshots, time.Duration(hours)*time.Hour)
+	}
+	// Usuarios que pueden acceder a las páginas de administración, separados por
+	// comas. Se ignoran los espacios y los nombres vacíos, como el de ADMIN_USERS=""
+	var admins []string
+	for _, username := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			admins = append(admins, username)
+		}
+	}
+
 	// Instancia del router Chi
 	r := chi.NewRouter()
 	// Middleware que loguea las peticiones en la consola
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	// Middleware que entrega las notificaciones (toasts) agregadas por los handlers
+	r.Use(flash.Middleware)
 
 	// Servidor de archivos estáticos para el CSS
 	r.Handle("/static/*", http.FileServer(http.FS(staticFS)))
@@ -76,7 +136,20 @@ func main() {
 
 	// Endpoint del formulario de login
 	r.Get("/login", func(w http.ResponseWriter, r *http.Request) {
-		handlers.Render(tpl, w, "login.html", nil)
+		handlers.Render(tpl, w, r, "login.html", nil)
+	})
+
+	// Calendario de vencimientos: el token secreto de la URL reemplaza a la sesion
+	r.Get("/calendario/{token}.ics", func(w http.ResponseWriter, r *http.Request) {
+		handlers.CalendarFeedHandler(w, r, queries)
+	})
+
+	// Notas compartidas con un enlace público: el token de la URL reemplaza a la sesion
+	r.Get("/publica/{token}", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PublicNoteHandler(w, r, tpl, queries, jwtSecret)
+	})
+	r.Post("/publica/{token}", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PublicNoteHandler(w, r, tpl, queries, jwtSecret)
 	})
 
 	// --- Rutas Protegidas ---
@@ -84,6 +157,8 @@ func main() {
 	r.Group(func(r chi.Router) {
 		// El middleware de autenticacion se encarga de validar la sesion
 		r.Use(authMiddleware.Authenticator(jwtSecret))
+		// Y este deja los espacios de trabajo del usuario y el elegido
+		r.Use(authMiddleware.LoadWorkspaces(queries))
 
 		// Todas las rutas aquí dentro requerirán un JWT válido.
 		// GET /notas renderiza la página de notas.
@@ -99,14 +174,159 @@ func main() {
 			handlers.CreateNoteFormHandler(w, r, tpl, queries)
 		})
 
+		// POST /vista_previa devuelve el Markdown del formulario renderizado
+		r.Post("/vista_previa", func(w http.ResponseWriter, r *http.Request) {
+			handlers.PreviewHandler(w, r, mdRenderer)
+		})
+
+		// POST /marcar_tarea/{id} marca o desmarca una tarea de la lista de una nota
+		r.Post("/marcar_tarea/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ToggleTaskHandler(w, r, tpl, queries, mdRenderer)
+		})
+
 		// POST /crear_nota para procesar el formulario
 		r.Post("/crear_nota", func(w http.ResponseWriter, r *http.Request) {
-			handlers.CreateNoteHandler(w, r, queries)
+			handlers.CreateNoteHandler(w, r, conn, queries, mdRenderer, reminderLead)
 		})
 
-		// DELETE /borrar_nota/{id} para borrar una nota
+		// DELETE /borrar_nota/{id} para enviar una nota a la papelera
 		r.Delete("/borrar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
-			handlers.DeleteNoteHandler(w, r, queries)
+			handlers.DeleteNoteHandler(w, r, tpl, queries)
+		})
+
+		// GET /papelera muestra las notas borradas
+		r.Get("/papelera", func(w http.ResponseWriter, r *http.Request) {
+			handlers.TrashHandler(w, r, tpl, queries, trashRetention)
+		})
+
+		// POST /restaurar_nota/{id} saca una nota de la papelera
+		r.Post("/restaurar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RestoreNoteHandler(w, r, tpl, queries)
+		})
+
+		// DELETE /eliminar_nota/{id} borra definitivamente una nota de la papelera
+		r.Delete("/eliminar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.PurgeNoteHandler(w, r, queries, blobStore)
+		})
+
+		// GET /archivo muestra las notas archivadas
+		r.Get("/archivo", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ArchiveHandler(w, r, tpl, queries)
+		})
+
+		// POST /archivar_nota/{id} archiva una nota
+		r.Post("/archivar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ArchiveNoteHandler(w, r, tpl, queries)
+		})
+
+		// POST /desarchivar_nota/{id} saca una nota del archivo
+		r.Post("/desarchivar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.UnarchiveNoteHandler(w, r, tpl, queries)
+		})
+
+		// POST /mover_nota/{id} cambia la posicion de una nota en el orden personalizado
+		r.Post("/mover_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.MoveNoteHandler(w, r, queries)
+		})
+
+		// GET /vencimientos muestra las notas vencidas y las proximas a vencer
+		r.Get("/vencimientos", func(w http.ResponseWriter, r *http.Request) {
+			handlers.DueNotesHandler(w, r, tpl, queries)
+		})
+
+		// GET /calendario muestra el enlace al calendario de vencimientos
+		r.Get("/calendario", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CalendarHandler(w, r, tpl, queries)
+		})
+
+		// POST /calendario/regenerar reemplaza el enlace al calendario
+		r.Post("/calendario/regenerar", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RegenerateCalendarTokenHandler(w, r, queries)
+		})
+
+		// GET /grafo muestra el grafo de notas y tags
+		r.Get("/grafo", func(w http.ResponseWriter, r *http.Request) {
+			handlers.GraphHandler(w, r, tpl, queries)
+		})
+
+		// GET /grafo.json devuelve los nodos y aristas del grafo
+		r.Get("/grafo.json", func(w http.ResponseWriter, r *http.Request) {
+			handlers.GraphDataHandler(w, r, queries)
+		})
+
+		// GET /exportar descarga todas las notas en un zip de archivos Markdown
+		r.Get("/exportar", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ExportHandler(w, r, queries)
+		})
+
+		// GET /importar muestra el formulario para importar notas
+		r.Get("/importar", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ImportHandler(w, r, tpl)
+		})
+
+		// POST /importar/vista_previa muestra qué notas se crearían sin guardarlas
+		r.Post("/importar/vista_previa", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ImportPreviewHandler(w, r, tpl, queries)
+		})
+
+		// POST /importar importa las notas de los archivos subidos
+		r.Post("/importar", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ImportNotesHandler(w, r, tpl, conn, queries, mdRenderer)
+		})
+
+		// GET /exportar.csv descarga las notas del listado en CSV, con los filtros de /notas
+		r.Get("/exportar.csv", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ExportCSVHandler(w, r, queries)
+		})
+
+		// GET /importar_csv muestra el formulario para importar notas desde un CSV
+		r.Get("/importar_csv", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ImportCSVHandler(w, r, tpl)
+		})
+
+		// POST /importar_csv/columnas muestra las columnas del CSV para elegir el mapeo
+		r.Post("/importar_csv/columnas", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CSVColumnsHandler(w, r, tpl)
+		})
+
+		// POST /importar_csv/vista_previa muestra qué notas se crearían con el CSV
+		r.Post("/importar_csv/vista_previa", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CSVPreviewHandler(w, r, tpl, queries)
+		})
+
+		// POST /importar_csv importa las filas del CSV como notas
+		r.Post("/importar_csv", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ImportCSVNotesHandler(w, r, tpl, conn, queries, mdRenderer)
+		})
+
+		// GET /tablero muestra las notas en columnas, una por tag
+		r.Get("/tablero", func(w http.ResponseWriter, r *http.Request) {
+			handlers.BoardHandler(w, r, tpl, queries)
+		})
+
+		// POST /mover_tarjeta/{id} mueve una nota a otra columna del tablero
+		r.Post("/mover_tarjeta/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.MoveCardHandler(w, r, tpl, conn, queries)
+		})
+
+		// POST /fijar_nota/{id} fija una nota al principio del listado
+		r.Post("/fijar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.PinNoteHandler(w, r, queries)
+		})
+
+		// POST /desfijar_nota/{id} quita una nota de las fijadas
+		r.Post("/desfijar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.UnpinNoteHandler(w, r, queries)
+		})
+
+		// GET /nota/{id} muestra una nota y las notas que la enlazan
+		r.Get("/nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.NoteHandler(w, r, tpl, queries)
+		})
+
+		// GET /ir_a_nota?nombre=... redirige a la nota con ese nombre (enlaces [[Nombre]])
+		r.Get("/ir_a_nota", func(w http.ResponseWriter, r *http.Request) {
+			handlers.GoToNoteHandler(w, r, queries)
 		})
 
 		// GET /editar_nota/{id} para mostrar el formulario de edición
@@ -116,7 +336,170 @@ func main() {
 
 		// POST /editar_nota/{id} para procesar el formulario de edición
 		r.Post("/editar_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
-			handlers.UpdateNoteHandler(w, r, queries)
+			handlers.UpdateNoteHandler(w, r, tpl, conn, queries, mdRenderer, reminderLead)
+		})
+
+		// POST /adjuntar/{id} sube un archivo adjunto a la nota
+		r.Post("/adjuntar/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.UploadAttachmentHandler(w, r, tpl, queries, blobStore, maxAttachmentSize)
+		})
+
+		// GET /adjunto/{id} descarga un archivo adjunto
+		r.Get("/adjunto/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.DownloadAttachmentHandler(w, r, queries, blobStore)
+		})
+
+		// GET /miniatura/{id} devuelve la miniatura de un adjunto de imagen
+		r.Get("/miniatura/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ThumbnailHandler(w, r, queries, blobStore)
+		})
+
+		// DELETE /borrar_adjunto/{id} borra un archivo adjunto
+		r.Delete("/borrar_adjunto/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.DeleteAttachmentHandler(w, r, queries, blobStore)
+		})
+
+		// GET /historial_nota/{id} muestra las versiones anteriores de una nota
+		r.Get("/historial_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.NoteHistoryHandler(w, r, tpl, queries)
+		})
+
+		// GET /diff_revision/{id} devuelve el diff de una revisión con la versión siguiente
+		r.Get("/diff_revision/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RevisionDiffHandler(w, r, tpl, queries)
+		})
+
+		// POST /restaurar_revision/{id} restaura una versión anterior de la nota
+		r.Post("/restaurar_revision/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RestoreRevisionHandler(w, r, conn, queries, mdRenderer)
+		})
+
+		// POST /comentar/{id} agrega un comentario a una nota
+		r.Post("/comentar/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CreateCommentHandler(w, r, tpl, queries)
+		})
+
+		// GET /comentario/{id} devuelve un comentario
+		r.Get("/comentario/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CommentHandler(w, r, tpl, queries)
+		})
+
+		// GET /editar_comentario/{id} devuelve el formulario para editar un comentario
+		r.Get("/editar_comentario/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.EditCommentFormHandler(w, r, tpl, queries)
+		})
+
+		// POST /editar_comentario/{id} guarda los cambios de un comentario
+		r.Post("/editar_comentario/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.UpdateCommentHandler(w, r, tpl, queries)
+		})
+
+		// DELETE /borrar_comentario/{id} borra un comentario
+		r.Delete("/borrar_comentario/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.DeleteCommentHandler(w, r, queries)
+		})
+
+		// GET /compartidas muestra las notas que otros usuarios compartieron conmigo
+		r.Get("/compartidas", func(w http.ResponseWriter, r *http.Request) {
+			handlers.SharedNotesHandler(w, r, tpl, queries)
+		})
+
+		// GET /compartir_nota/{id} muestra con quién está compartida una nota
+		r.Get("/compartir_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ShareNoteFormHandler(w, r, tpl, queries)
+		})
+
+		// POST /compartir_nota/{id} comparte una nota con otro usuario
+		r.Post("/compartir_nota/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.ShareNoteHandler(w, r, queries)
+		})
+
+		// DELETE /compartir_nota/{id}/{usuario} deja de compartir una nota con un usuario
+		r.Delete("/compartir_nota/{id}/{usuario}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.UnshareNoteHandler(w, r, queries)
+		})
+
+		// GET /enlaces_publicos/{id} muestra los enlaces públicos de una nota
+		r.Get("/enlaces_publicos/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.PublicLinksHandler(w, r, tpl, queries)
+		})
+
+		// POST /enlaces_publicos/{id} crea un enlace público para una nota
+		r.Post("/enlaces_publicos/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CreatePublicLinkHandler(w, r, queries)
+		})
+
+		// DELETE /enlace_publico/{id} revoca un enlace público
+		r.Delete("/enlace_publico/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RevokePublicLinkHandler(w, r, queries)
+		})
+
+		// GET /espacios muestra mis espacios de trabajo
+		r.Get("/espacios", func(w http.ResponseWriter, r *http.Request) {
+			handlers.WorkspacesHandler(w, r, tpl, queries)
+		})
+
+		// POST /espacios crea un espacio de trabajo
+		r.Post("/espacios", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CreateWorkspaceHandler(w, r, conn, queries)
+		})
+
+		// POST /espacio cambia el espacio de trabajo elegido
+		r.Post("/espacio", func(w http.ResponseWriter, r *http.Request) {
+			handlers.SwitchWorkspaceHandler(w, r, queries)
+		})
+
+		// GET /espacios/{id} muestra los miembros y las invitaciones de un espacio
+		r.Get("/espacios/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.WorkspaceHandler(w, r, tpl, queries)
+		})
+
+		// POST /espacios/{id}/invitaciones crea un enlace de invitación
+		r.Post("/espacios/{id}/invitaciones", func(w http.ResponseWriter, r *http.Request) {
+			handlers.CreateInvitationHandler(w, r, queries)
+		})
+
+		// POST /espacios/{id}/miembros/{usuario} cambia el rol de un miembro
+		r.Post("/espacios/{id}/miembros/{usuario}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.SetMemberRoleHandler(w, r, queries)
+		})
+
+		// DELETE /espacios/{id}/miembros/{usuario} quita un miembro o deja el espacio
+		r.Delete("/espacios/{id}/miembros/{usuario}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RemoveMemberHandler(w, r, queries)
+		})
+
+		// DELETE /invitacion/{id} revoca un enlace de invitación
+		r.Delete("/invitacion/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.RevokeInvitationHandler(w, r, queries)
+		})
+
+		// GET y POST /invitaciones/{token} muestran y aceptan una invitación
+		r.Get("/invitaciones/{token}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.InvitationHandler(w, r, tpl, queries)
+		})
+		r.Post("/invitaciones/{token}", func(w http.ResponseWriter, r *http.Request) {
+			handlers.InvitationHandler(w, r, tpl, queries)
+		})
+
+		// --- Rutas de administración (usuarios de ADMIN_USERS) ---
+		r.Group(func(r chi.Router) {
+			r.Use(authMiddleware.AdminOnly(admins))
+
+			// GET /admin/copias muestra las copias de la base de datos
+			r.Get("/admin/copias", func(w http.ResponseWriter, r *http.Request) {
+				handlers.SnapshotsHandler(w, r, tpl, snapshots)
+			})
+
+			// POST /admin/copias crea una copia de la base de datos
+			r.Post("/admin/copias", func(w http.ResponseWriter, r *http.Request) {
+				handlers.CreateSnapshotHandler(w, r, conn, snapshots)
+			})
+
+			// GET /admin/copias/{nombre} descarga una copia
+			r.Get("/admin/copias/{nombre}", func(w http.ResponseWriter, r *http.Request) {
+				handlers.DownloadSnapshotHandler(w, r, snapshots)
+			})
 		})
 	})
 
@@ -154,3 +537,62 @@ func createTestUser(ctx context.Context, queries *db.Queries) {
 		log.Printf("Usuario de prueba '%s' creado con contraseña '%s'", username, password)
 	}
 }
+
+// newReminderNotifier crea el notificador de recordatorios elegido en REMINDER_NOTIFIER
+// (log, smtp o webhook). Por defecto los recordatorios se escriben en el log.
+func newReminderNotifier() reminder.Notifier {
+	switch kind := os.Getenv("REMINDER_NOTIFIER"); kind {
+	case "", "log":
+		return reminder.LogNotifier{}
+	case "smtp":
+		n := reminder.SMTPNotifier{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       strings.Split(os.Getenv("SMTP_TO"), ","),
+		}
+		if n.Addr == "" || n.From == "" || os.Getenv("SMTP_TO") == "" {
+			log.Fatal("REMINDER_NOTIFIER=smtp requiere SMTP_ADDR, SMTP_FROM y SMTP_TO.")
+		}
+		return n
+	case "webhook":
+		url := os.Getenv("REMINDER_WEBHOOK_URL")
+		if url == "" {
+			log.Fatal("REMINDER_NOTIFIER=webhook requiere REMINDER_WEBHOOK_URL.")
+		}
+		return reminder.WebhookNotifier{URL: url}
+	default:
+		log.Fatalf("REMINDER_NOTIFIER inválido: %q", kind)
+		return nil
+	}
+}
+
+// newSnapshotManager configura las copias de la base de datos: directorio
+// (SNAPSHOT_DIR), cantidad a conservar (SNAPSHOT_KEEP) y antigüedad máxima
+// en días (SNAPSHOT_RETENTION_DAYS). 0 desactiva cada límite.
+func newSnapshotManager() *snapshot.Manager {
+	dir := os.Getenv("SNAPSHOT_DIR")
+	if dir == "" {
+		dir = "./copias"
+	}
+	return &snapshot.Manager{
+		Dir:    dir,
+		Keep:   envInt("SNAPSHOT_KEEP", 7),
+		MaxAge: time.Duration(envInt("SNAPSHOT_RETENTION_DAYS", 30)) * 24 * time.Hour,
+	}
+}
+
+// envInt lee una variable de entorno entera no negativa, o devuelve def si no esta definida.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("%s inválido: %q", name, value)
+	}
+	return n
+}
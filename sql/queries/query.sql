@@ -1,8 +1,8 @@
 -- sql/queries/query.sql
 
 -- name: CreateTag :one
-INSERT INTO tags (nombre, color)
-VALUES (?, ?)
+INSERT INTO tags (nombre, color, workspace_id)
+VALUES (?, ?, ?)
 RETURNING *;
 
 -- name: GetTag :one
@@ -11,30 +11,91 @@ WHERE id = ? LIMIT 1;
 
 -- name: ListTags :many
 SELECT * FROM tags
+WHERE workspace_id IS ?
 ORDER BY nombre;
 
+-- name: ListAllTags :many
+SELECT * FROM tags
+ORDER BY id;
+
+-- name: CopyGeneralTags :exec
+INSERT INTO tags (nombre, color, workspace_id)
+SELECT nombre, color, @workspace_id FROM tags
+WHERE workspace_id IS NULL;
+
 -- name: CreateNote :one
-INSERT INTO notes (nombre, contenido)
-VALUES (?, ?)
+INSERT INTO notes (nombre, contenido, position, user_id, workspace_id)
+VALUES (?, ?, ?, ?, ?)
 RETURNING *;
 
+-- name: GetFirstNotePosition :one
+SELECT position FROM notes
+WHERE id != ?
+ORDER BY position
+LIMIT 1;
+
+-- name: MoveNote :exec
+UPDATE notes
+SET position = ?
+WHERE id = ?;
+
 -- name: ListNotes :many
 SELECT * FROM notes
+WHERE deleted_at IS NULL
 ORDER BY id DESC;
 
 -- name: GetNote :one
 SELECT * FROM notes
 WHERE id = ? LIMIT 1;
 
--- name: UpdateNote :exec
+-- name: UpdateNote :execrows
 UPDATE notes
-SET nombre = ?, contenido = ?
-WHERE id = ?;
+SET nombre = ?, contenido = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = ? AND version = ?;
 
 -- name: DeleteNote :exec
 DELETE FROM notes
 WHERE id = ?;
 
+-- name: TrashNote :exec
+UPDATE notes
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: RestoreNote :exec
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = ?;
+
+-- name: ListTrashedNotes :many
+SELECT * FROM notes
+WHERE deleted_at IS NOT NULL AND workspace_id IS @workspace_id AND (workspace_id IS NOT NULL OR user_id = @user_id)
+ORDER BY deleted_at DESC;
+
+-- name: PurgeTrashedNotes :execrows
+DELETE FROM notes
+WHERE deleted_at IS NOT NULL AND deleted_at < @cutoff;
+
+-- name: PinNote :exec
+UPDATE notes
+SET pinned_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: UnpinNote :exec
+UPDATE notes
+SET pinned_at = NULL
+WHERE id = ?;
+
+-- name: ArchiveNote :exec
+UPDATE notes
+SET archived_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL AND archived_at IS NULL;
+
+-- name: UnarchiveNote :exec
+UPDATE notes
+SET archived_at = NULL
+WHERE id = ?;
+
 -- name: UnlinkTagsFromNote :exec
 DELETE FROM note_tags
 WHERE note_id = ?;
@@ -43,6 +104,14 @@ WHERE note_id = ?;
 INSERT INTO note_tags (note_id, tag_id)
 VALUES (?, ?);
 
+-- name: UnlinkTagFromNote :exec
+DELETE FROM note_tags
+WHERE note_id = ? AND tag_id = ?;
+
+-- name: AddTagToNote :exec
+INSERT OR IGNORE INTO note_tags (note_id, tag_id)
+VALUES (?, ?);
+
 -- name: GetTagsForNote :many
 SELECT t.* FROM tags t
 JOIN note_tags nt ON t.id = nt.tag_id
@@ -57,11 +126,32 @@ RETURNING *;
 SELECT * FROM users
 WHERE username = ? LIMIT 1;
 
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = ? LIMIT 1;
+
+-- name: GetUserByCalendarToken :one
+SELECT * FROM users
+WHERE calendar_token = ? LIMIT 1;
+
+-- name: SetUserCalendarToken :exec
+UPDATE users
+SET calendar_token = ?
+WHERE id = ?;
+
 -- name: ListNotesWithTags :many
 SELECT
     n.id AS note_id,
     n.nombre AS note_nombre,
     n.contenido AS note_contenido,
+    n.created_at AS note_created_at,
+    n.updated_at AS note_updated_at,
+    n.version AS note_version,
+    n.pinned_at AS note_pinned_at,
+    n.archived_at AS note_archived_at,
+    n.position AS note_position,
+    n.due_at AS note_due_at,
+    n.user_id AS note_user_id,
     t.id AS tag_id,
     t.nombre AS tag_nombre,
     t.color AS tag_color
@@ -71,5 +161,400 @@ FROM
     note_tags nt ON n.id = nt.note_id
         LEFT JOIN
     tags t ON nt.tag_id = t.id
+WHERE
+    n.deleted_at IS NULL
+    AND n.workspace_id IS @workspace_id
+    AND (n.workspace_id IS NOT NULL OR n.user_id = @user_id)
 ORDER BY
-    n.id DESC;
\ No newline at end of file
+    n.id DESC;
+
+-- name: CreateNoteRevision :one
+INSERT INTO note_revisions (note_id, nombre, contenido, edited_at)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetNoteRevision :one
+SELECT * FROM note_revisions
+WHERE id = ? LIMIT 1;
+
+-- name: ListNoteRevisions :many
+SELECT * FROM note_revisions
+WHERE note_id = ?
+ORDER BY id DESC;
+
+-- name: GetNextNoteRevision :one
+SELECT * FROM note_revisions
+WHERE note_id = ? AND id > ?
+ORDER BY id
+LIMIT 1;
+
+
+-- name: CreateAttachment :one
+INSERT INTO attachments (note_id, filename, content_type, size, sha256, thumbnail_sha256)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE id = ? LIMIT 1;
+
+-- name: ListAttachmentsForNote :many
+SELECT * FROM attachments
+WHERE note_id = ?
+ORDER BY id;
+
+-- name: ListAttachments :many
+SELECT a.* FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NULL
+    AND n.workspace_id IS @workspace_id
+    AND (n.workspace_id IS NOT NULL OR n.user_id = @user_id)
+ORDER BY a.id;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments
+WHERE id = ?;
+
+-- name: CountAttachmentsBySHA256 :one
+SELECT COUNT(*) FROM attachments
+WHERE sha256 = @sha256 OR thumbnail_sha256 = @sha256;
+
+-- name: ListPurgeableAttachmentHashes :many
+SELECT a.sha256 FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NOT NULL AND n.deleted_at < @cutoff
+UNION
+SELECT a.thumbnail_sha256 FROM attachments a
+JOIN notes n ON n.id = a.note_id
+WHERE n.deleted_at IS NOT NULL AND n.deleted_at < @cutoff AND a.thumbnail_sha256 IS NOT NULL;
+
+
+-- name: SetNoteDueAt :exec
+UPDATE notes
+SET due_at = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = ?;
+
+-- name: CreateReminder :exec
+INSERT OR IGNORE INTO reminders (note_id, fire_at)
+VALUES (?, ?);
+
+-- name: DeletePendingReminders :exec
+DELETE FROM reminders
+WHERE note_id = ? AND sent_at IS NULL;
+
+-- name: ListDueReminders :many
+SELECT
+    r.id,
+    r.note_id,
+    r.fire_at,
+    r.attempts,
+    n.nombre,
+    n.due_at
+FROM reminders r
+JOIN notes n ON n.id = r.note_id
+WHERE r.sent_at IS NULL
+  AND (r.claimed_at IS NULL OR r.claimed_at <= @stale)
+  AND r.fire_at <= @now
+  AND r.attempts < @max_attempts
+  AND n.deleted_at IS NULL
+ORDER BY r.fire_at;
+
+-- name: ClaimReminder :execrows
+UPDATE reminders
+SET claimed_at = @claimed_at
+WHERE id = @id AND sent_at IS NULL
+  AND (claimed_at IS NULL OR claimed_at <= @stale);
+
+-- name: MarkReminderSent :exec
+UPDATE reminders
+SET sent_at = ?, claimed_at = NULL
+WHERE id = ?;
+
+-- name: ReleaseReminder :exec
+UPDATE reminders
+SET claimed_at = NULL, attempts = attempts + 1, last_error = ?
+WHERE id = ?;
+
+-- name: GetNoteByName :one
+SELECT * FROM notes
+WHERE workspace_id IS @workspace_id AND (workspace_id IS NOT NULL OR user_id = @user_id)
+    AND nombre = @nombre COLLATE NOCASE AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1;
+
+-- name: DeleteNoteLinksFrom :exec
+DELETE FROM note_links
+WHERE source_id = ?;
+
+-- name: CreateNoteLink :exec
+INSERT OR IGNORE INTO note_links (source_id, target_id)
+VALUES (?, ?);
+
+-- name: ListBacklinks :many
+SELECT n.* FROM notes n
+JOIN note_links l ON l.source_id = n.id
+WHERE l.target_id = ?
+ORDER BY n.nombre;
+
+-- name: ListAllBacklinks :many
+SELECT l.target_id, n.id AS source_id, n.nombre AS source_nombre
+FROM note_links l
+JOIN notes n ON n.id = l.source_id
+WHERE n.deleted_at IS NULL
+    AND n.workspace_id IS @workspace_id
+    AND (n.workspace_id IS NOT NULL OR n.user_id = @user_id)
+ORDER BY n.nombre;
+
+-- name: ListNotesMentioning :many
+SELECT * FROM notes
+WHERE workspace_id IS @workspace_id AND (workspace_id IS NOT NULL OR user_id = @user_id)
+    AND instr(lower(contenido), lower(@texto)) > 0;
+
+-- name: RewriteNoteContent :exec
+UPDATE notes
+SET contenido = ?, version = version + 1
+WHERE id = ?;
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY id;
+
+-- name: ListAllNotes :many
+SELECT * FROM notes
+ORDER BY id;
+
+-- name: ListNoteTags :many
+SELECT * FROM note_tags
+ORDER BY note_id, tag_id;
+
+-- name: ListNoteLinks :many
+SELECT * FROM note_links
+ORDER BY source_id, target_id;
+
+-- name: CountBackupRows :one
+SELECT
+    (SELECT COUNT(*) FROM users) AS users,
+    (SELECT COUNT(*) FROM notes) AS notes,
+    (SELECT COUNT(*) FROM tags) AS tags,
+    (SELECT COUNT(*) FROM workspaces) AS workspaces;
+
+-- name: InsertBackupUser :one
+INSERT INTO users (username, password_hash, calendar_token)
+VALUES (?, ?, ?)
+RETURNING id;
+
+-- name: InsertBackupNote :one
+INSERT INTO notes (nombre, contenido, created_at, updated_at, deleted_at, version, pinned_at, archived_at, position, due_at, user_id, workspace_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id;
+
+-- name: SetBackupNoteContent :exec
+UPDATE notes
+SET contenido = ?
+WHERE id = ?;
+
+-- name: ListNoteShares :many
+SELECT * FROM note_shares
+ORDER BY note_id, user_id;
+
+-- name: InsertBackupNoteShare :exec
+INSERT INTO note_shares (note_id, user_id, permission, created_at)
+VALUES (?, ?, ?, ?);
+
+-- name: ShareNote :exec
+INSERT INTO note_shares (note_id, user_id, permission)
+VALUES (?, ?, ?)
+ON CONFLICT (note_id, user_id) DO UPDATE SET permission = excluded.permission;
+
+-- name: UnshareNote :execrows
+DELETE FROM note_shares
+WHERE note_id = ? AND user_id = ?;
+
+-- name: GetNoteShare :one
+SELECT * FROM note_shares
+WHERE note_id = ? AND user_id = ?;
+
+-- name: ListSharesForNote :many
+SELECT s.user_id, u.username, s.permission, s.created_at
+FROM note_shares s
+JOIN users u ON u.id = s.user_id
+WHERE s.note_id = ?
+ORDER BY u.username;
+
+-- name: ListNotesSharedWith :many
+SELECT n.id, u.username AS owner_username, s.permission
+FROM note_shares s
+JOIN notes n ON n.id = s.note_id
+JOIN users u ON u.id = n.user_id
+WHERE s.user_id = ? AND n.deleted_at IS NULL
+ORDER BY n.updated_at DESC;
+
+-- name: CreatePublicLink :one
+INSERT INTO public_links (note_id, token, password_hash, expires_at)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetPublicLink :one
+SELECT * FROM public_links
+WHERE id = ? LIMIT 1;
+
+-- name: GetPublicLinkByToken :one
+SELECT * FROM public_links
+WHERE token = ? LIMIT 1;
+
+-- name: ListPublicLinksForNote :many
+SELECT * FROM public_links
+WHERE note_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: CountPublicLinkView :exec
+UPDATE public_links
+SET views = views + 1, last_viewed_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: FailPublicLinkPassword :exec
+-- Al llegar a @max_attempts intentos fallidos se bloquea el enlace hasta
+-- @locked_until y se vuelve a contar desde cero.
+UPDATE public_links
+SET failed_attempts = CASE WHEN failed_attempts + 1 >= @max_attempts THEN 0 ELSE failed_attempts + 1 END,
+    locked_until = CASE WHEN failed_attempts + 1 >= @max_attempts THEN @locked_until ELSE locked_until END
+WHERE id = @id;
+
+-- name: ResetPublicLinkAttempts :exec
+UPDATE public_links
+SET failed_attempts = 0, locked_until = NULL
+WHERE id = ?;
+
+-- name: DeletePublicLink :exec
+DELETE FROM public_links
+WHERE id = ?;
+
+-- name: CreateWorkspace :one
+INSERT INTO workspaces (nombre)
+VALUES (?)
+RETURNING *;
+
+-- name: GetWorkspace :one
+SELECT * FROM workspaces
+WHERE id = ? LIMIT 1;
+
+-- name: ListWorkspacesForUser :many
+SELECT w.id, w.nombre, m.role
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE m.user_id = ?
+ORDER BY w.nombre COLLATE NOCASE;
+
+-- name: GetWorkspaceMember :one
+SELECT * FROM workspace_members
+WHERE workspace_id = ? AND user_id = ?;
+
+-- name: AddWorkspaceMember :exec
+INSERT INTO workspace_members (workspace_id, user_id, role)
+VALUES (?, ?, ?)
+ON CONFLICT (workspace_id, user_id) DO NOTHING;
+
+-- name: SetWorkspaceMemberRole :execrows
+-- Un administrador solo deja de serlo si queda otro en el espacio. La cuenta
+-- se hace en la misma sentencia para que dos cambios simultaneos no dejen el
+-- espacio sin administradores.
+UPDATE workspace_members
+SET role = @role
+WHERE workspace_members.workspace_id = @workspace_id AND workspace_members.user_id = @user_id
+  AND (workspace_members.role = @role OR workspace_members.role != 'admin' OR (
+    SELECT COUNT(*) FROM workspace_members a
+    WHERE a.workspace_id = @workspace_id AND a.role = 'admin'
+  ) > 1);
+
+-- name: RemoveWorkspaceMember :execrows
+-- Igual que SetWorkspaceMemberRole, no se quita al ultimo administrador.
+DELETE FROM workspace_members
+WHERE workspace_members.workspace_id = @workspace_id AND workspace_members.user_id = @user_id
+  AND (workspace_members.role != 'admin' OR (
+    SELECT COUNT(*) FROM workspace_members a
+    WHERE a.workspace_id = @workspace_id AND a.role = 'admin'
+  ) > 1);
+
+-- name: ListWorkspaceMembers :many
+SELECT m.user_id, u.username, m.role, m.created_at
+FROM workspace_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.workspace_id = ?
+ORDER BY u.username;
+
+-- name: CreateWorkspaceInvitation :one
+INSERT INTO workspace_invitations (workspace_id, token, role, created_by, expires_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWorkspaceInvitation :one
+SELECT * FROM workspace_invitations
+WHERE id = ? LIMIT 1;
+
+-- name: GetWorkspaceInvitationByToken :one
+SELECT * FROM workspace_invitations
+WHERE token = ? LIMIT 1;
+
+-- name: ListWorkspaceInvitations :many
+SELECT * FROM workspace_invitations
+WHERE workspace_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: CountWorkspaceInvitationUse :exec
+UPDATE workspace_invitations
+SET uses = uses + 1
+WHERE id = ?;
+
+-- name: DeleteWorkspaceInvitation :exec
+DELETE FROM workspace_invitations
+WHERE id = ?;
+
+-- name: ListAllWorkspaces :many
+SELECT * FROM workspaces
+ORDER BY id;
+
+-- name: ListAllWorkspaceMembers :many
+SELECT * FROM workspace_members
+ORDER BY workspace_id, user_id;
+
+-- name: InsertBackupWorkspace :one
+INSERT INTO workspaces (nombre, created_at)
+VALUES (?, ?)
+RETURNING id;
+
+-- name: InsertBackupWorkspaceMember :exec
+INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+VALUES (?, ?, ?, ?);
+
+-- name: CreateComment :one
+INSERT INTO note_comments (note_id, user_id, contenido)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM note_comments
+WHERE id = ? LIMIT 1;
+
+-- name: ListCommentsForNote :many
+SELECT c.id, c.note_id, c.user_id, c.contenido, c.created_at, c.updated_at, u.username
+FROM note_comments c
+JOIN users u ON u.id = c.user_id
+WHERE c.note_id = ?
+ORDER BY c.created_at, c.id;
+
+-- name: UpdateComment :one
+UPDATE note_comments
+SET contenido = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteComment :exec
+DELETE FROM note_comments
+WHERE id = ?;
+
+-- name: ListAllComments :many
+SELECT * FROM note_comments
+ORDER BY id;
+
+-- name: InsertBackupComment :exec
+INSERT INTO note_comments (note_id, user_id, contenido, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?);
\ No newline at end of file
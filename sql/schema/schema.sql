@@ -1,15 +1,28 @@
 -- sql/schema/schema.sql
 
+-- El nombre es unico dentro de cada espacio de trabajo (NULL son las tags
+-- generales). El indice unico lo crea database/sqlite.go despues de migrar.
 CREATE TABLE IF NOT EXISTS tags (
     "id"    INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-    "nombre" TEXT NOT NULL UNIQUE,
-    "color"  TEXT
+    "nombre" TEXT NOT NULL,
+    "color"  TEXT,
+    "workspace_id" INTEGER REFERENCES workspaces(id) ON DELETE CASCADE
 );
 
 CREATE TABLE IF NOT EXISTS notes (
     "id"        INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-    "nombre"    TEXT NOT NULL,
-    "contenido" TEXT
+    "nombre"     TEXT NOT NULL,
+    "contenido"  TEXT,
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "deleted_at" DATETIME,
+    "version"    INTEGER NOT NULL DEFAULT 1,
+    "pinned_at"   DATETIME,
+    "archived_at" DATETIME,
+    "position"    TEXT NOT NULL DEFAULT '',
+    "due_at"      DATETIME,
+    "user_id"     INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    "workspace_id" INTEGER REFERENCES workspaces(id) ON DELETE CASCADE
 );
 
 CREATE TABLE IF NOT EXISTS note_tags (
@@ -23,5 +36,113 @@ CREATE TABLE IF NOT EXISTS note_tags (
 CREATE TABLE IF NOT EXISTS users (
     "id"            INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
     "username"      TEXT NOT NULL UNIQUE,
-    "password_hash" TEXT NOT NULL
+    "password_hash" TEXT NOT NULL,
+    "calendar_token" TEXT
+);
+
+CREATE TABLE IF NOT EXISTS note_revisions (
+    "id"         INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "note_id"    INTEGER NOT NULL,
+    "nombre"     TEXT NOT NULL,
+    "contenido"  TEXT,
+    "edited_at"  DATETIME NOT NULL,
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS attachments (
+    "id"               INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "note_id"          INTEGER NOT NULL,
+    "filename"         TEXT NOT NULL,
+    "content_type"     TEXT NOT NULL,
+    "size"             INTEGER NOT NULL,
+    "sha256"           TEXT NOT NULL,
+    "thumbnail_sha256" TEXT,
+    "created_at"       DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+
+CREATE TABLE IF NOT EXISTS reminders (
+    "id"         INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "note_id"    INTEGER NOT NULL,
+    "fire_at"    DATETIME NOT NULL,
+    "sent_at"    DATETIME,
+    "claimed_at" DATETIME,
+    "attempts"   INTEGER NOT NULL DEFAULT 0,
+    "last_error" TEXT,
+    UNIQUE(note_id, fire_at),
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_links (
+    "source_id" INTEGER NOT NULL,
+    "target_id" INTEGER NOT NULL,
+    PRIMARY KEY(source_id, target_id),
+    FOREIGN KEY(source_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY(target_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_shares (
+    "note_id"    INTEGER NOT NULL,
+    "user_id"    INTEGER NOT NULL,
+    "permission" TEXT NOT NULL CHECK (permission IN ('lectura', 'edicion')),
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(note_id, user_id),
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS public_links (
+    "id"             INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "note_id"        INTEGER NOT NULL,
+    "token"          TEXT NOT NULL UNIQUE,
+    "password_hash"  TEXT,
+    "expires_at"     DATETIME,
+    "views"          INTEGER NOT NULL DEFAULT 0,
+    "last_viewed_at" DATETIME,
+    "created_at"     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "failed_attempts" INTEGER NOT NULL DEFAULT 0,
+    "locked_until"   DATETIME,
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    "id"         INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "nombre"     TEXT NOT NULL,
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    "workspace_id" INTEGER NOT NULL,
+    "user_id"      INTEGER NOT NULL,
+    "role"         TEXT NOT NULL CHECK (role IN ('admin', 'miembro', 'lector')),
+    "created_at"   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(workspace_id, user_id),
+    FOREIGN KEY(workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    "id"           INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "workspace_id" INTEGER NOT NULL,
+    "token"        TEXT NOT NULL UNIQUE,
+    "role"         TEXT NOT NULL CHECK (role IN ('admin', 'miembro', 'lector')),
+    "created_by"   INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    "uses"         INTEGER NOT NULL DEFAULT 0,
+    "expires_at"   DATETIME NOT NULL,
+    "created_at"   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_comments (
+    "id"         INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    "note_id"    INTEGER NOT NULL,
+    "user_id"    INTEGER NOT NULL,
+    "contenido"  TEXT NOT NULL,
+    "created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" DATETIME,
+    FOREIGN KEY(note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
\ No newline at end of file